@@ -2,8 +2,8 @@ package client
 
 import (
 	"context"
-	"database/sql"
 	"database/sql/driver"
+	"sync/atomic"
 
 	"github.com/codenotary/immudb/pkg/api/schema"
 	"github.com/codenotary/immudb/pkg/client"
@@ -14,22 +14,56 @@ import (
 type immudbConn struct {
 	client client.ImmuClient
 	tx     client.Tx
+	logger common.Logger
+	// policy defines how queries are logged.
+	policy common.LogPolicy
+	// uuidFormat is the format in which UUID values are returned.
+	uuidFormat common.UUIDFormat
+	// lastResult is the result of the last statement executed outside
+	// of a transaction or transaction committed using the connection.
+	lastResult common.Result
+	// txReadOnly is set while a read only transaction is active.
+	txReadOnly bool
+	// poisoned is set once the session has expired or the server became
+	// unavailable. The connection is discarded, before it is reused.
+	poisoned atomic.Bool
+}
+
+// Options configure a connection to an immudb instance.
+type Options struct {
+	// Client are the options used for connecting to immudb.
+	Client *client.Options
+	// UUIDFormat is the format in which UUID values are returned.
+	UUIDFormat common.UUIDFormat
+	Logger     common.Logger
+	// Policy defines how queries are logged.
+	Policy common.LogPolicy
 }
 
 // Connect establishes a new connection to an immudb instance.
-func Open(ctx context.Context, options *client.Options) (driver.Conn, error) {
+func Open(ctx context.Context, opts Options) (driver.Conn, error) {
+	data := map[string]any{
+		"address":  opts.Client.Bind(),
+		"database": opts.Client.Database,
+	}
 	// Connect to immudb.
 	c := client.NewClient()
-	c = c.WithOptions(options)
-	err := c.OpenSession(ctx, []byte(options.Username), []byte(options.Password), options.Database)
+	c = c.WithOptions(opts.Client)
+	err := c.OpenSession(ctx, []byte(opts.Client.Username), []byte(opts.Client.Password), opts.Client.Database)
 	if err != nil {
+		data["err"] = err
+		common.Log(ctx, opts.Logger, common.LogLevelError, "opening connection failed", data)
 		return nil, err
 	}
 	// Create the connection with the just received auth token for the database.
 	conn := &immudbConn{
-		client: c,
-		tx:     nil,
+		client:     c,
+		tx:         nil,
+		logger:     opts.Logger,
+		policy:     opts.Policy,
+		uuidFormat: opts.UUIDFormat,
 	}
+	common.Log(ctx, opts.Logger, common.LogLevelInfo, "connection opened", data)
 	return conn, nil
 }
 
@@ -37,7 +71,7 @@ func Open(ctx context.Context, options *client.Options) (driver.Conn, error) {
 
 // Prepare prepares a sql statement.
 func (conn *immudbConn) Prepare(query string) (driver.Stmt, error) {
-	return &stmt{query: query}, nil
+	return &stmt{query: query, conn: conn}, nil
 }
 
 // Begin start a new transaction.
@@ -51,8 +85,14 @@ func (conn *immudbConn) Close() error {
 	// Closing the session, will also disconnect from the server,
 	// but disconnecting tht client will leave the session active an results in
 	// a lot of error messages being dumped onto the console.
-	return conn.client.CloseSession(context.Background())
+	err := conn.client.CloseSession(context.Background())
 	// return conn.client.Disconnect()
+	if err != nil {
+		conn.log(context.Background(), common.LogLevelError, "closing connection failed", map[string]any{"err": err})
+		return err
+	}
+	conn.log(context.Background(), common.LogLevelInfo, "connection closed", nil)
+	return nil
 }
 
 // -- ConnBeginTx interface --
@@ -62,21 +102,32 @@ func (conn *immudbConn) BeginTx(ctx context.Context, opts driver.TxOptions) (dri
 	if conn.tx != nil {
 		return nil, common.ErrNestedTxNotSupported
 	}
-	// Check if the transaction should be read only, which is currently not supported.
+	// A connection without a valid session has to be replaced.
+	if conn.poisoned.Load() {
+		return nil, driver.ErrBadConn
+	}
+	// Unsupported isolation levels have to be rejected
+	// according to the database/sql documentation.
+	unsafeMVCC, err := common.CheckIsolation(opts.Isolation)
+	if err != nil {
+		return nil, err
+	}
+	var txOpts []client.TxOption
 	if opts.ReadOnly {
-		return nil, common.ErrReadOnlyTxNotSupported
+		txOpts = append(txOpts, readOnlyTx)
 	}
-	// Check if an isolation level has been set for the transaction.
-	// Immudb does not support setting a specific isolation level,
-	// therefore an error has to be returned according to the database/sql documentation.
-	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
-		return nil, common.ErrIsolationLevelNotSupported
+	if unsafeMVCC {
+		txOpts = append(txOpts, client.UnsafeMVCC())
 	}
-	immuTx, err := conn.client.NewTx(ctx)
+	immuTx, err := conn.client.NewTx(ctx, txOpts...)
 	if err != nil {
-		return nil, err
+		conn.log(ctx, common.LogLevelError, "beginning transaction failed", map[string]any{"err": err})
+		// Beginning a transaction can always be retried using another connection.
+		return nil, conn.handleError(ctx, err, true)
 	}
 	conn.tx = immuTx
+	conn.txReadOnly = opts.ReadOnly
+	conn.log(ctx, common.LogLevelDebug, "transaction started", nil)
 	return &tx{conn: conn, ctx: ctx}, nil
 }
 
@@ -118,11 +169,20 @@ func (conn *immudbConn) QueryContext(ctx context.Context, query string, args []d
 func (conn *immudbConn) Ping(ctx context.Context) error {
 	// Check if the client is connected.
 	if !conn.client.IsConnected() {
+		conn.log(ctx, common.LogLevelWarn, "ping failed: client is not connected", nil)
+		return driver.ErrBadConn
+	}
+	// Check if the session is known to have expired.
+	if conn.poisoned.Load() {
+		conn.log(ctx, common.LogLevelWarn, "ping failed: session is no longer valid", nil)
 		return driver.ErrBadConn
 	}
-	// Perform a health check.
+	// Perform a health check. As the request is sent
+	// using the session, it also verifies that it is valid.
 	_, err := conn.client.ServerInfo(ctx, &schema.ServerInfoRequest{})
 	if err != nil {
+		conn.log(ctx, common.LogLevelWarn, "ping failed", map[string]any{"err": err})
+		conn.poison(err)
 		return driver.ErrBadConn
 	}
 	return nil
@@ -135,14 +195,27 @@ func (conn *immudbConn) Ping(ctx context.Context) error {
 func (conn *immudbConn) ResetSession(ctx context.Context) error {
 	// Check if the client is connected.
 	if !conn.client.IsConnected() {
+		conn.log(ctx, common.LogLevelWarn, "resetting session failed: client is not connected", nil)
+		return driver.ErrBadConn
+	}
+	// Check if the session has expired or the server became unavailable,
+	// while the connection was in use or idle in the pool.
+	if conn.poisoned.Load() {
+		conn.log(ctx, common.LogLevelWarn, "resetting session failed: session is no longer valid", nil)
 		return driver.ErrBadConn
 	}
 	// Switch to the original database, if the current database,
 	// is different from the database which was used at the start of the session.
 	opts := conn.client.GetOptions()
 	if opts.CurrentDatabase != opts.Database {
+		data := map[string]any{
+			"from": opts.CurrentDatabase,
+			"to":   opts.Database,
+		}
 		dbs, err := conn.client.DatabaseListV2(ctx)
 		if err != nil {
+			data["err"] = err
+			conn.log(ctx, common.LogLevelWarn, "switching database failed", data)
 			return driver.ErrBadConn
 		}
 		var origDB *schema.DatabaseInfo
@@ -152,16 +225,40 @@ func (conn *immudbConn) ResetSession(ctx context.Context) error {
 			}
 		}
 		if origDB == nil {
+			conn.log(ctx, common.LogLevelWarn, "switching database failed: database not found", data)
 			return driver.ErrBadConn
 		}
 		_, err = conn.client.UseDatabase(ctx, &schema.Database{DatabaseName: origDB.Name})
 		if err != nil {
+			data["err"] = err
+			conn.log(ctx, common.LogLevelWarn, "switching database failed", data)
 			return driver.ErrBadConn
 		}
+		conn.log(ctx, common.LogLevelInfo, "database switched", data)
 	}
+	conn.lastResult = nil
+	conn.log(ctx, common.LogLevelDebug, "session reset", nil)
 	return nil
 }
 
+// -- NamedValueChecker interface --
+
+// CheckNamedValue converts the parameters of a statement
+// into values supported by immudb.
+func (conn *immudbConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return common.CheckNamedValue(nv)
+}
+
+// -- Validator interface --
+
+// IsValid is called by database/sql before the connection is returned to the
+// pool. Only local checks are performed, as it must not block. A connection is
+// discarded, if the client has been disconnected, the session has been closed
+// or an error occurred, after which the connection must not be used anymore.
+func (conn *immudbConn) IsValid() bool {
+	return conn.client.IsConnected() && conn.client.GetSessionID() != "" && !conn.poisoned.Load()
+}
+
 // -- ImmuDB custom interface --
 
 // ExistTable checks if a table with the given name exist in the connected database.
@@ -185,3 +282,52 @@ func (conn *immudbConn) ExistTable(name string) (bool, error) {
 	}
 	return false, nil
 }
+
+// LastResult returns the result of the last statement executed outside
+// of a transaction or transaction committed using the connection.
+func (conn *immudbConn) LastResult() common.Result {
+	return conn.lastResult
+}
+
+// -- util --
+
+// readOnlyTx is an option of the client creating a read only transaction.
+func readOnlyTx(req *schema.NewTxRequest) error {
+	req.Mode = schema.TxMode_ReadOnly
+	return nil
+}
+
+// handleError translates an error reported by the server. If the session has
+// expired or the server is unavailable, the connection is poisoned.
+// Outside of a transaction driver.ErrBadConn is returned in this case, so that
+// database/sql retries the request using another connection. The server never
+// executes a request of an expired session. If it was unavailable though, the
+// request may have been executed, hence retry must only be set for requests,
+// which can be repeated safely. Within a transaction the error is always
+// returned, as the transaction cannot be continued using another connection.
+func (conn *immudbConn) handleError(ctx context.Context, err error, retry bool) error {
+	expired := common.IsSessionExpired(err)
+	if !conn.poison(err) {
+		return common.TranslateError(err)
+	}
+	if conn.tx == nil && (expired || retry) {
+		conn.log(ctx, common.LogLevelWarn, "retrying using another connection", map[string]any{"err": err})
+		return driver.ErrBadConn
+	}
+	return common.TranslateError(err)
+}
+
+// poison marks the connection as no longer usable, if the error shows that the
+// session has expired or the server is unavailable. It reports if it did so.
+func (conn *immudbConn) poison(err error) bool {
+	if !common.IsSessionExpired(err) && !common.IsUnavailable(err) {
+		return false
+	}
+	conn.poisoned.Store(true)
+	return true
+}
+
+// log writes a message to the logger of the connection.
+func (conn *immudbConn) log(ctx context.Context, level common.LogLevel, msg string, data map[string]any) {
+	common.Log(ctx, conn.logger, level, msg, data)
+}
@@ -0,0 +1,296 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/codenotary/immudb/pkg/database"
+	"github.com/tauu/immusql/common"
+)
+
+// historyPageSize is the number of transactions scanned at once.
+const historyPageSize = 100
+
+// History retrieves all revisions of the row of a table with the given primary key.
+// The revisions are ordered by the transactions, which stored them.
+// The values of the revisions are read from the history of the table, while
+// the transactions storing them are searched for using period clauses.
+// The server does not provide the history of the keys of rows, as its
+// history only covers keys set using the key value interface.
+func (conn *immudbConn) History(ctx context.Context, table string, pk ...any) ([]common.Revision, error) {
+	quotedTable, err := common.QuoteIdentifier(table)
+	if err != nil {
+		return nil, err
+	}
+	pkCols, err := conn.primaryKey(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := newPKSelector(table, pkCols, pk)
+	if err != nil {
+		return nil, err
+	}
+	// The history of a table contains deleted revisions as well,
+	// but only provides the number of each revision.
+	query := "SELECT * FROM (HISTORY OF " + quotedTable + ") WHERE " + sel.condition
+	res, err := conn.client.SQLQuery(ctx, query, sel.params, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rows) == 0 {
+		return nil, nil
+	}
+	revisions := make([]common.Revision, len(res.Rows))
+	for _, row := range res.Rows {
+		values := make(map[string]any, len(row.Columns)-1)
+		var rev int64
+		for i, col := range row.Columns {
+			name := columnName(col)
+			if name == "_rev" {
+				rev, _ = schema.RawValue(row.Values[i]).(int64)
+				continue
+			}
+			values[name] = schema.RawValue(row.Values[i])
+		}
+		if rev < 1 || rev > int64(len(revisions)) {
+			return nil, fmt.Errorf("unexpected revision %d of a row in table %s", rev, table)
+		}
+		revisions[rev-1].Values = values
+	}
+	err = conn.revisionTxs(ctx, table, quotedTable, pkCols, sel, revisions)
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// revisionTxs sets the transaction of each revision of a row, starting with
+// the latest one. A query using the period clause SINCE TX a UNTIL TX b finds
+// the row, if its latest revision until b has been stored by a transaction
+// after a and has not been deleted. The transaction of a revision is therefore
+// found using a binary search. Revisions deleting the row are not found by any
+// query and are searched for by scanning the transactions backwards instead,
+// starting with the transaction before the next revision of the row.
+func (conn *immudbConn) revisionTxs(ctx context.Context, table, quotedTable string, pkCols []string, sel *pkSelector, revisions []common.Revision) error {
+	state, err := conn.client.CurrentState(ctx)
+	if err != nil {
+		return err
+	}
+	// until is the last transaction, which may have stored the revision.
+	until := state.TxId
+	for rev := len(revisions); rev > 0; rev-- {
+		if until == 0 {
+			return fmt.Errorf("the transactions of %d revisions of a row in table %s were not found", rev, table)
+		}
+		revision := &revisions[rev-1]
+		found, err := conn.rowStored(ctx, quotedTable, sel, 1, until)
+		if err != nil {
+			return err
+		}
+		var hdr *schema.TxHeader
+		if found {
+			hdr, err = conn.searchRevision(ctx, quotedTable, sel, until)
+		} else {
+			revision.Deleted = true
+			hdr, err = conn.scanDeletion(ctx, table, pkCols, sel, until)
+		}
+		if err != nil {
+			return err
+		}
+		revision.TxID = hdr.Id
+		revision.Timestamp = time.Unix(hdr.Ts, 0)
+		until = hdr.Id - 1
+	}
+	return nil
+}
+
+// rowStored checks if the latest revision of the row until the transaction
+// until has been stored after the transaction since and has not been deleted.
+func (conn *immudbConn) rowStored(ctx context.Context, quotedTable string, sel *pkSelector, since, until uint64) (bool, error) {
+	params := make(map[string]interface{}, len(sel.params)+2)
+	for name, value := range sel.params {
+		params[name] = value
+	}
+	params["since"] = int64(since)
+	params["until"] = int64(until)
+	query := "SELECT COUNT(*) FROM " + quotedTable + " SINCE TX @since UNTIL TX @until WHERE " + sel.condition
+	res, err := conn.client.SQLQuery(ctx, query, params, false)
+	if err != nil {
+		return false, err
+	}
+	if len(res.Rows) != 1 || len(res.Rows[0].Values) != 1 {
+		return false, fmt.Errorf("unexpected result counting the revisions of a row")
+	}
+	return res.Rows[0].Values[0].GetN() > 0, nil
+}
+
+// searchRevision searches the transaction, which stored the latest revision of
+// the row until the given transaction. The revision must not have been deleted.
+func (conn *immudbConn) searchRevision(ctx context.Context, quotedTable string, sel *pkSelector, until uint64) (*schema.TxHeader, error) {
+	// The row is found since the first transaction, but not since the one after until.
+	low, high := uint64(1), until
+	for low < high {
+		mid := low + (high-low+1)/2
+		found, err := conn.rowStored(ctx, quotedTable, sel, mid, until)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return conn.txHeader(ctx, low)
+}
+
+// txHeader retrieves the header of a transaction.
+func (conn *immudbConn) txHeader(ctx context.Context, txID uint64) (*schema.TxHeader, error) {
+	list, err := conn.client.TxScan(ctx, &schema.TxScanRequest{
+		InitialTx: txID,
+		Limit:     1,
+		EntriesSpec: &schema.EntriesSpec{
+			KvEntriesSpec:  &schema.EntryTypeSpec{Action: schema.EntryTypeAction_EXCLUDE},
+			ZEntriesSpec:   &schema.EntryTypeSpec{Action: schema.EntryTypeAction_EXCLUDE},
+			SqlEntriesSpec: &schema.EntryTypeSpec{Action: schema.EntryTypeAction_EXCLUDE},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Txs) == 0 || list.Txs[0].Header.GetId() != txID {
+		return nil, fmt.Errorf("transaction %d not found", txID)
+	}
+	return list.Txs[0].Header, nil
+}
+
+// scanDeletion scans the transactions backwards starting with the given one,
+// until the transaction deleting the row has been found. It is the first
+// transaction found storing the row, as its latest revision has been deleted.
+// A transaction contains an entry for every row it stores. Its key consists of
+// the id of the table and the encoded primary key and its metadata marks deleted rows.
+func (conn *immudbConn) scanDeletion(ctx context.Context, table string, pkCols []string, sel *pkSelector, until uint64) (*schema.TxHeader, error) {
+	encPK, err := conn.encodePK(ctx, table, pkCols, sel.values)
+	if err != nil {
+		return nil, err
+	}
+	prefix := sql.MapKey([]byte{database.SQLPrefix}, sql.RowPrefix, sql.EncodeID(sql.DatabaseID))
+	suffix := append(sql.EncodeID(sql.PKIndexID), encPK...)
+	// Other tables may contain rows with the same primary key.
+	// tables records for the id of each table, whether it is the table of the row.
+	tables := map[uint32]bool{}
+	for txID := until; txID > 0; {
+		list, err := conn.client.TxScan(ctx, &schema.TxScanRequest{
+			InitialTx: txID,
+			Limit:     historyPageSize,
+			Desc:      true,
+			EntriesSpec: &schema.EntriesSpec{
+				KvEntriesSpec:  &schema.EntryTypeSpec{Action: schema.EntryTypeAction_EXCLUDE},
+				ZEntriesSpec:   &schema.EntryTypeSpec{Action: schema.EntryTypeAction_EXCLUDE},
+				SqlEntriesSpec: &schema.EntryTypeSpec{Action: schema.EntryTypeAction_ONLY_DIGEST},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(list.Txs) == 0 {
+			break
+		}
+		for _, tx := range list.Txs {
+			txID = tx.Header.Id - 1
+			for _, entry := range tx.Entries {
+				key := entry.Key
+				if len(key) != len(prefix)+sql.EncIDLen+len(suffix) ||
+					!bytes.HasPrefix(key, prefix) || !bytes.HasSuffix(key, suffix) {
+					continue
+				}
+				tableID := binary.BigEndian.Uint32(key[len(prefix):])
+				own, known := tables[tableID]
+				if !known {
+					own, err = conn.ownsTable(ctx, table, sel, tableID, tx.Header.Id)
+					if err != nil {
+						return nil, err
+					}
+					tables[tableID] = own
+				}
+				if !own {
+					continue
+				}
+				if !entry.Metadata.GetDeleted() {
+					return nil, fmt.Errorf("the revision of a row in table %s stored by transaction %d has not been deleted", table, tx.Header.Id)
+				}
+				return tx.Header, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("the transaction deleting a row in table %s was not found", table)
+}
+
+// ownsTable checks if the id belongs to the given table, which stored
+// a revision of the row selected by the primary key in the transaction.
+func (conn *immudbConn) ownsTable(ctx context.Context, table string, sel *pkSelector, tableID uint32, txID uint64) (bool, error) {
+	entry, err := conn.client.GetServiceClient().VerifiableSQLGet(ctx, &schema.VerifiableSQLGetRequest{
+		SqlGetRequest: &schema.SQLGetRequest{
+			Table:    table,
+			PkValues: sel.sqlValues,
+			AtTx:     txID,
+		},
+	})
+	if common.IsKeyNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return entry.TableId == tableID, nil
+}
+
+// encodePK encodes the values of the primary key of a table as immudb does in keys.
+func (conn *immudbConn) encodePK(ctx context.Context, table string, pkCols []string, values []driver.Value) ([]byte, error) {
+	res, err := conn.client.SQLQuery(ctx, "SELECT * FROM COLUMNS(@table)", map[string]interface{}{"table": table}, false)
+	if err != nil {
+		return nil, err
+	}
+	nameIndex, typeIndex, lenIndex := -1, -1, -1
+	for i, col := range res.Columns {
+		switch columnName(col.Name) {
+		case "name":
+			nameIndex = i
+		case "type":
+			typeIndex = i
+		case "max_length":
+			lenIndex = i
+		}
+	}
+	if nameIndex < 0 || typeIndex < 0 || lenIndex < 0 {
+		return nil, fmt.Errorf("unexpected columns in the columns of table %s", table)
+	}
+	var encPK []byte
+	for i, col := range pkCols {
+		found := false
+		for _, row := range res.Rows {
+			if name, _ := schema.RawValue(row.Values[nameIndex]).(string); name != col {
+				continue
+			}
+			colType, _ := schema.RawValue(row.Values[typeIndex]).(string)
+			maxLen, _ := schema.RawValue(row.Values[lenIndex]).(int64)
+			encValue, _, err := sql.EncodeRawValueAsKey(values[i], sql.SQLValueType(colType), int(maxLen))
+			if err != nil {
+				return nil, err
+			}
+			encPK = append(encPK, encValue...)
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("column %s of table %s not found", col, table)
+		}
+	}
+	return encPK, nil
+}
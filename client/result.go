@@ -1,12 +1,16 @@
 package client
 
 import (
+	"context"
 	"database/sql/driver"
-	"fmt"
+	"errors"
 	"io"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/codenotary/immudb/embedded/sql"
 	"github.com/codenotary/immudb/pkg/api/schema"
 	"github.com/codenotary/immudb/pkg/client"
 	"github.com/tauu/immusql/common"
@@ -14,47 +18,29 @@ import (
 
 // result contains the data reported by immudb after executing a statement.
 type result struct {
+	// TxResult contains the metadata of the committed transactions.
+	common.TxResult
 	data *schema.SQLExecResult
-	// previousLastPks map[string]int64
+	// insertedPKs contains the last primary key
+	// inserted by the statement into each table.
+	insertedPKs map[string]int64
 }
 
 // -- Result interface --
 
 // LastInsertId returns the id of the last row inserted by a statement.
+// If the statement inserted rows into more than one table with an auto
+// increment primary key, the id is ambiguous and -1 is returned.
+// LastInsertedPKs provides the ids for each table in this case. Statements
+// executed as part of a transaction report -1, as the server only reports
+// the inserted keys, once the transaction is committed.
 func (r result) LastInsertId() (int64, error) {
-	// TODO this function is not working as intended currently.
-	// tx.GetLastInsertedPKs will never return a primary key, as service is not
-	// providing any to the client during a transaction. Therefore this driver
-	// can also not provide the last inserted IDs during a transaction.
-	// The only way to fix it, is by modifying the immudb client and server.
-	if r.data == nil {
-		return -1, nil
-	}
-	// Get the last executed transaction.
-	txs := r.data.GetTxs()
-	if len(txs) < 1 {
-		return -1, nil
-	}
-	tx := txs[len(txs)-1]
-	// If there was more then one primary key in the last transaction,
-	// it is currently not possible to determine, which was created last.
-	// In this case no PK is returned.
-	pks := tx.GetLastInsertedPKs()
-	if len(pks) == 1 {
-		for _, pk := range pks {
-			// If the numeric value of pk is 0, the primary key is not an integer.
-			// In this case -1 is returned, as database/sql does not support
-			// any other type for primary keys.
-			n := pk.GetN()
-			if n == 0 {
-				return -1, nil
-			}
-			return n, nil
+	if len(r.insertedPKs) == 1 {
+		for _, id := range r.insertedPKs {
+			return id, nil
 		}
 	}
-
 	return -1, nil
-
 }
 
 // RowsAffected returns the number of rows affected by executing a statement.
@@ -75,10 +61,55 @@ func (r result) RowsAffected() (int64, error) {
 	return count, nil
 }
 
+// LastInsertedPKs returns the last value assigned by the
+// statement to the auto increment primary key of each table.
+func (r result) LastInsertedPKs() map[string]int64 {
+	pks := make(map[string]int64, len(r.insertedPKs))
+	for table, id := range r.insertedPKs {
+		pks[table] = id
+	}
+	return pks
+}
+
+// txResult collects the metadata of the committed transactions.
+func txResult(txs []*schema.CommittedSQLTx) common.TxResult {
+	res := make(common.TxResult, 0, len(txs))
+	for _, tx := range txs {
+		if tx.GetHeader() == nil {
+			continue
+		}
+		hdr := schema.TxHeaderFromProto(tx.GetHeader())
+		pks := make(map[string]int64, len(tx.GetLastInsertedPKs()))
+		for table, pk := range tx.GetLastInsertedPKs() {
+			pks[table] = pk.GetN()
+		}
+		res = append(res, common.TxInfo{
+			ID:              hdr.ID,
+			Timestamp:       time.Unix(hdr.Ts, 0),
+			Hash:            hdr.Alh(),
+			LastInsertedPKs: pks,
+		})
+	}
+	return res
+}
+
 // rows contains the rows retrieved by immudb after executing a query.
 type rows struct {
-	data  client.SQLQueryRowReader
-	index int
+	data   client.SQLQueryRowReader
+	index  int
+	logger common.Logger
+	// ctx is the context of the query, which is used
+	// for looking up the definition of the columns.
+	ctx context.Context
+	// query is the text of the query, which is used
+	// for resolving the tables of the columns.
+	query string
+	// client is used for looking up the definition of the columns.
+	client client.ImmuClient
+	// infos caches the definition of each column, once it has been looked up.
+	infos []*common.ColumnInfo
+	// uuidFormat is the format in which UUID values are returned.
+	uuidFormat common.UUIDFormat
 }
 
 // -- Rows interface --
@@ -90,22 +121,26 @@ func (r *rows) Columns() []string {
 	// Create a sting array and insert all names into it.
 	columns := make([]string, len(immudbCols))
 	for i, col := range immudbCols {
-		name := col.Name
-		// immudb returns column names enclosed in ( )
-		// and also includes the name of the database.
-		// database/sql expects only the column name though,
-		// and therefore these values have to be stripped.
-		name = strings.TrimSuffix(name, ")")
-		name = strings.TrimPrefix(name, "(")
-		index := strings.LastIndex(name, ".")
-		if index > -1 {
-			name = name[index+1:]
-		}
-		columns[i] = name
+		columns[i] = columnName(col.Name)
 	}
 	return columns
 }
 
+// columnName strips the decorations immudb adds to the name of a column.
+func columnName(name string) string {
+	// immudb returns column names enclosed in ( )
+	// and also includes the name of the database.
+	// database/sql expects only the column name though,
+	// and therefore these values have to be stripped.
+	name = strings.TrimSuffix(name, ")")
+	name = strings.TrimPrefix(name, "(")
+	index := strings.LastIndex(name, ".")
+	if index > -1 {
+		name = name[index+1:]
+	}
+	return name
+}
+
 // Close closes the query result iterator.
 func (r *rows) Close() error {
 	// Currently there is nothing to close,
@@ -115,18 +150,50 @@ func (r *rows) Close() error {
 
 // Next returns the next row of the query result.
 func (r *rows) Next(dest []driver.Value) error {
-	// Check if the last row has already been read.
+	// Check if the last row has already been read. Errors of the stream,
+	// e.g. if the query has been interrupted, are only reported by Read.
 	if !r.data.Next() {
-		return io.EOF
+		_, err := r.data.Read()
+		if err == nil || errors.Is(err, sql.ErrNoMoreRows) {
+			return io.EOF
+		}
+		// The stream fails, once the context of the query is done.
+		if ctxErr := r.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return common.TranslateError(err)
 	}
 	// Get the next row.
 	row, err := r.data.Read()
 	if err != nil {
-		return err
+		return common.TranslateError(err)
 	}
 	// Write value to destination slice.
+	cols := r.data.Columns()
 	for i, value := range row {
-		dest[i] = value
+		// Abort if not enough destination values exist.
+		if i >= len(dest) {
+			break
+		}
+		text, ok := value.(string)
+		if !ok || i >= len(cols) {
+			dest[i] = value
+			continue
+		}
+		switch cols[i].Type {
+		case sql.JSONType:
+			// The server transfers JSON values as their JSON text. They are
+			// returned as bytes just like by the embedded engine.
+			dest[i] = []byte(text)
+		case sql.UUIDType:
+			// The server transfers uuids using their string representation.
+			dest[i], err = common.UUIDValue(text, r.uuidFormat)
+			if err != nil {
+				return err
+			}
+		default:
+			dest[i] = value
+		}
 	}
 	// Advance the index.
 	r.index = r.index + 1
@@ -143,18 +210,31 @@ func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
 		return ""
 	}
 	typeName := immudbCols[index].Type
-	fmt.Printf("typeName: %v\n", typeName)
+	common.Log(r.ctx, r.logger, common.LogLevelTrace, "column type retrieved", map[string]any{
+		"index": index,
+		"type":  typeName,
+	})
 	return typeName
 }
 
 // -- RowsColumnTypeNullable interface --
 
 // ColumnTypeNullable returns if the index-th column in the result is nullable.
+// It is only known for columns, which are read directly from a table.
 func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
-	// Immudb supports nullable columns,
-	// but there does not seem to be a way to determine
-	// if a column can be nullable using only the result of a query.
-	return false, true
+	info := r.columnInfo(index)
+	if info == nil {
+		return false, false
+	}
+	return info.Nullable, true
+}
+
+// -- RowsColumnTypeLength interface --
+
+// ColumnTypeLength returns the maximum length of the index-th column in the
+// result, if it has a variable length type.
+func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	return common.ColumnTypeLength(r.ColumnTypeDatabaseTypeName(index), r.columnInfo(index))
 }
 
 // -- RowsColumnTypePrecisionScale interface --
@@ -175,5 +255,52 @@ func (r *rows) ColumnTypeScanType(index int) reflect.Type {
 		return nil
 	}
 	typeName := immudbCols[index].Type
-	return common.ColumnTypeScanType(typeName)
+	return common.ColumnTypeScanType(typeName, r.uuidFormat)
+}
+
+// -- helper --
+
+// columnInfo returns the definition of the index-th column in the catalog.
+// The definitions of all columns are looked up once and cached afterwards.
+func (r *rows) columnInfo(index int) *common.ColumnInfo {
+	if r.infos == nil {
+		immudbCols := r.data.Columns()
+		selectors := make([]string, len(immudbCols))
+		for i, col := range immudbCols {
+			selectors[i] = col.Name
+		}
+		r.infos = common.ColumnInfos(r.query, selectors, r.describeTable)
+	}
+	if index >= len(r.infos) {
+		return nil
+	}
+	return r.infos[index]
+}
+
+// describeTable retrieves the definition of the columns of a table.
+func (r *rows) describeTable(name string) (map[string]common.ColumnInfo, error) {
+	res, err := r.client.DescribeTable(r.ctx, name)
+	if err != nil {
+		common.Log(r.ctx, r.logger, common.LogLevelDebug, "describing table failed", map[string]any{"table": name, "err": err})
+		return nil, err
+	}
+	// Each row describes a column using the values
+	// COLUMN, TYPE, NULLABLE, INDEX, AUTO_INCREMENT and UNIQUE.
+	cols := make(map[string]common.ColumnInfo, len(res.GetRows()))
+	for _, row := range res.GetRows() {
+		values := row.GetValues()
+		if len(values) < 4 {
+			continue
+		}
+		// Columns of the primary key can never be NULL.
+		info := common.ColumnInfo{
+			Nullable: values[2].GetB() && values[3].GetS() != "PRIMARY KEY",
+		}
+		// The maximum length is appended to the type, e.g. VARCHAR(10).
+		if _, maxLen, ok := strings.Cut(values[1].GetS(), "("); ok {
+			info.MaxLen, _ = strconv.Atoi(strings.TrimSuffix(maxLen, ")"))
+		}
+		cols[values[0].GetS()] = info
+	}
+	return cols, nil
 }
@@ -3,8 +3,10 @@ package client
 import (
 	"context"
 	"database/sql/driver"
-	"strconv"
+	"strings"
+	"time"
 
+	"github.com/codenotary/immudb/embedded/sql"
 	"github.com/codenotary/immudb/pkg/api/schema"
 	"github.com/codenotary/immudb/pkg/client"
 	"github.com/tauu/immusql/common"
@@ -56,11 +58,31 @@ func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
 // ExecContext executes the statement and returns the result.
 // This method if required to satisfy the StmtExecContext interface of sql/driver.
 func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	// Statements modifying data are rejected in read only transactions,
+	// before they are sent to the server. This keeps the transaction usable.
+	// If the statement cannot be parsed, the server reports the error.
+	if s.conn.tx != nil && s.conn.txReadOnly {
+		stmts, err := sql.ParseSQL(strings.NewReader(s.query))
+		if err == nil && !common.ReadOnly(stmts) {
+			common.LogQuery(ctx, s.conn.logger, s.conn.policy, "statement executed", s.query, args, start, common.ErrReadOnlyTx)
+			return nil, common.ErrReadOnlyTx
+		}
+	}
+
+	// A connection without a valid session has to be replaced.
+	if s.conn.tx == nil && s.conn.poisoned.Load() {
+		return nil, driver.ErrBadConn
+	}
 
 	// Convert arguments to the expected format and execute the query.
-	params := namedValueToMapString(args)
+	params := common.NamedValueToMapString(args)
 	// Execute the query as part of the transaction,
 	// if there is an active transaction.
+	// The server only reports the primary keys inserted as part of a
+	// transaction, once it is committed. They are not looked up before,
+	// as additional queries would be part of the transaction as well.
 	var res *schema.SQLExecResult
 	var err error
 	if s.conn.tx != nil {
@@ -68,11 +90,20 @@ func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (drive
 	} else {
 		res, err = s.conn.client.SQLExec(ctx, s.query, params)
 	}
+	common.LogQuery(ctx, s.conn.logger, s.conn.policy, "statement executed", s.query, args, start, err)
 	if err != nil {
-		return nil, err
+		// The statement may have been executed, if the server became unavailable.
+		return nil, s.conn.handleError(ctx, err, false)
+	}
+	// Statements executed as part of a transaction
+	// do not commit any transactions on their own.
+	r := result{data: res}
+	if res != nil {
+		r.TxResult = txResult(res.GetTxs())
+		r.insertedPKs = r.TxResult.LastInsertedPKs()
+		s.conn.lastResult = r
 	}
-	//fmt.Printf("exec response data: %s", res.String())
-	return result{data: res}, nil
+	return r, nil
 }
 
 // -- StmtQueryContext interface --
@@ -80,41 +111,32 @@ func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (drive
 // QueryContext executes the statement and returns the retrieved rows.
 // This method if required to satisfy the StmtQueryContext interface of sql/driver.
 func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	// A connection without a valid session has to be replaced.
+	if s.conn.tx == nil && s.conn.poisoned.Load() {
+		return nil, driver.ErrBadConn
+	}
 	// Convert arguments to the expected format and execute the query.
-	params := namedValueToMapString(args)
+	params := common.NamedValueToMapString(args)
+	// Read the snapshot requested by the context.
+	query, err := common.ApplySnapshot(ctx, s.query)
+	if err != nil {
+		return nil, err
+	}
 	// Execute the query as part of the transaction,
 	// if there is an active transaction.
 	var res client.SQLQueryRowReader
-	var err error
 	if s.conn.tx != nil {
-		res, err = s.conn.tx.SQLQueryReader(ctx, s.query, params)
-		//res, err = s.conn.tx.SQLQuery(ctx, s.query, params)
+		res, err = s.conn.tx.SQLQueryReader(ctx, query, params)
+		//res, err = s.conn.tx.SQLQuery(ctx, query, params)
 	} else {
-		res, err = s.conn.client.SQLQueryReader(ctx, s.query, params)
-		//res, err = s.conn.client.SQLQuery(ctx, s.query, params, false)
+		res, err = s.conn.client.SQLQueryReader(ctx, query, params)
+		//res, err = s.conn.client.SQLQuery(ctx, query, params, false)
 	}
+	common.LogQuery(ctx, s.conn.logger, s.conn.policy, "query executed", query, args, start, err)
 	if err != nil {
-		return nil, err
-	}
-	return &rows{data: res, index: 0}, nil
-}
-
-// -- utils --
-
-// namedValueToMapString converts the arguments to a statement from the datastruct driver.NamedValue
-// defined by sql.Driver to map[string]interface, which is expected by immuclient.
-func namedValueToMapString(args []driver.NamedValue) map[string]interface{} {
-	res := make(map[string]interface{})
-	for _, namedValue := range args {
-		name := namedValue.Name
-		if name == "" {
-			// Positional arguments get names assigned
-			// following the schema "paramORDINAL" with ORDINAL being an integer.
-			// See embedded/sql/sql_parser.go and embedded/sql/sql_grammar.y
-			// for details how the parameters are parsed and named.
-			name = "param" + strconv.Itoa(namedValue.Ordinal)
-		}
-		res[name] = namedValue.Value
+		// Queries do not modify any data and can therefore be retried.
+		return nil, s.conn.handleError(ctx, err, true)
 	}
-	return res
+	return &rows{data: res, index: 0, logger: s.conn.logger, ctx: ctx, query: s.query, client: s.conn.client, uuidFormat: s.conn.uuidFormat}, nil
 }
@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 
+	"github.com/codenotary/immudb/pkg/api/schema"
+
 	"github.com/tauu/immusql/common"
 )
 
@@ -20,10 +22,23 @@ func (t *tx) Commit() error {
 	if t.conn.tx == nil {
 		return common.ErrTxAlreadyFinished
 	}
+	// Immudb refuses to commit read only transactions. As they cannot
+	// contain any changes, they are completed by a rollback instead.
+	if t.conn.txReadOnly {
+		return t.release()
+	}
 	// Commit the transaction.
-	_, err := t.conn.tx.Commit(t.ctx)
+	committed, err := t.conn.tx.Commit(t.ctx)
 	t.finish()
-	return err
+	if err != nil {
+		t.conn.log(t.ctx, common.LogLevelError, "committing transaction failed", map[string]any{"err": err})
+		// The connection is discarded before it is reused, if the session expired.
+		t.conn.poison(err)
+		return common.TranslateError(err)
+	}
+	t.conn.lastResult = txResult([]*schema.CommittedSQLTx{committed})
+	t.conn.log(t.ctx, common.LogLevelDebug, "transaction committed", nil)
+	return nil
 }
 
 // Rollback rolls back the transaction.
@@ -35,7 +50,27 @@ func (t *tx) Rollback() error {
 	// Rollback the transaction.
 	err := t.conn.tx.Rollback(t.ctx)
 	t.finish()
-	return err
+	if err != nil {
+		t.conn.log(t.ctx, common.LogLevelError, "rolling back transaction failed", map[string]any{"err": err})
+		t.conn.poison(err)
+		return common.TranslateError(err)
+	}
+	t.conn.log(t.ctx, common.LogLevelDebug, "transaction rolled back", nil)
+	return nil
+}
+
+// release completes a read only transaction by rolling it back.
+func (t *tx) release() error {
+	err := t.conn.tx.Rollback(t.ctx)
+	t.finish()
+	if err != nil {
+		t.conn.log(t.ctx, common.LogLevelError, "rolling back read only transaction failed", map[string]any{"err": err})
+		t.conn.poison(err)
+		return common.TranslateError(err)
+	}
+	t.conn.lastResult = common.TxResult{}
+	t.conn.log(t.ctx, common.LogLevelDebug, "read only transaction rolled back", nil)
+	return nil
 }
 
 // finish completes the transaction, and informs the connection
@@ -43,4 +78,5 @@ func (t *tx) Rollback() error {
 func (t *tx) finish() {
 	// If the transaction was completed, remove it from the connection.
 	t.conn.tx = nil
+	t.conn.txReadOnly = false
 }
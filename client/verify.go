@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/api/schema"
+	"github.com/tauu/immusql/common"
+)
+
+// VerifiedGet retrieves the row of a table with the given primary key.
+// The row is verified against the state of the server persisted locally by the client.
+func (conn *immudbConn) VerifiedGet(table string, pk ...any) (map[string]any, error) {
+	ctx := context.Background()
+	quotedTable, err := common.QuoteIdentifier(table)
+	if err != nil {
+		return nil, err
+	}
+	// Determine the columns of the primary key.
+	pkCols, err := conn.primaryKey(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	// Select the row with the primary key.
+	sel, err := newPKSelector(table, pkCols, pk)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT * FROM " + quotedTable + " WHERE " + sel.condition
+	res, err := conn.client.SQLQuery(ctx, query, sel.params, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	// Verify the row using the proofs provided by the server.
+	row := res.Rows[0]
+	err = conn.client.VerifyRow(ctx, row, table, sel.sqlValues)
+	if errors.Is(err, store.ErrCorruptedData) {
+		conn.log(ctx, common.LogLevelError, "verifying row failed", map[string]any{"table": table, "err": err})
+		return nil, fmt.Errorf("%w: %w", common.ErrVerificationFailed, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(row.Columns))
+	for i, col := range row.Columns {
+		values[columnName(col)] = schema.RawValue(row.Values[i])
+	}
+	return values, nil
+}
+
+// primaryKey returns the names of the columns of the primary key of a table.
+func (conn *immudbConn) primaryKey(ctx context.Context, table string) ([]string, error) {
+	res, err := conn.client.SQLQuery(ctx, "SELECT * FROM INDEXES(@table)", map[string]interface{}{"table": table}, false)
+	if err != nil {
+		return nil, err
+	}
+	nameIndex, primaryIndex := -1, -1
+	for i, col := range res.Columns {
+		switch columnName(col.Name) {
+		case "name":
+			nameIndex = i
+		case "primary":
+			primaryIndex = i
+		}
+	}
+	if nameIndex < 0 || primaryIndex < 0 {
+		return nil, fmt.Errorf("unexpected columns in the indexes of table %s", table)
+	}
+	for _, row := range res.Rows {
+		if primary, _ := schema.RawValue(row.Values[primaryIndex]).(bool); !primary {
+			continue
+		}
+		// The name of an index consists of the table name
+		// followed by the columns of the index, e.g. table(col1,col2).
+		name, _ := schema.RawValue(row.Values[nameIndex]).(string)
+		name = strings.TrimPrefix(name, table+"(")
+		name = strings.TrimSuffix(name, ")")
+		return strings.Split(name, ","), nil
+	}
+	return nil, fmt.Errorf("table %s has no primary key", table)
+}
+
+// pkSelector selects a row of a table using the values of its primary key.
+type pkSelector struct {
+	// condition compares the columns of the primary key with the parameters.
+	condition string
+	params    map[string]interface{}
+	values    []driver.Value
+	sqlValues []*schema.SQLValue
+}
+
+// newPKSelector creates a selector for the given values of the primary key columns of a table.
+func newPKSelector(table string, pkCols []string, pk []any) (*pkSelector, error) {
+	if len(pk) != len(pkCols) {
+		return nil, fmt.Errorf("%w: table %s has %d primary key columns, but %d values were given",
+			common.ErrPrimaryKeyMismatch, table, len(pkCols), len(pk))
+	}
+	sel := &pkSelector{
+		params:    make(map[string]interface{}, len(pk)),
+		values:    make([]driver.Value, len(pk)),
+		sqlValues: make([]*schema.SQLValue, len(pk)),
+	}
+	conditions := make([]string, len(pk))
+	for i, col := range pkCols {
+		value, err := driver.DefaultParameterConverter.ConvertValue(pk[i])
+		if err != nil {
+			return nil, err
+		}
+		sel.sqlValues[i], err = schema.AsSQLValue(value)
+		if err != nil {
+			return nil, err
+		}
+		quotedCol, err := common.QuoteIdentifier(col)
+		if err != nil {
+			return nil, err
+		}
+		name := "pk" + strconv.Itoa(i)
+		sel.params[name] = value
+		sel.values[i] = value
+		conditions[i] = quotedCol + " = @" + name
+	}
+	sel.condition = strings.Join(conditions, " AND ")
+	return sel, nil
+}
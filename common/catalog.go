@@ -0,0 +1,56 @@
+package common
+
+import "strings"
+
+// ColumnInfo describes a column as it is defined in the catalog of a database.
+type ColumnInfo struct {
+	// Nullable is set, if the column may contain NULL values.
+	Nullable bool
+	// MaxLen is the maximum length of VARCHAR and BLOB columns.
+	// It is zero, if the length is not limited.
+	MaxLen int
+}
+
+// SplitSelector splits the selector identifying a column of a query result
+// into the aggregate function, the table and the name of the column.
+// Immudb encodes selectors using the format aggFn(table.column).
+func SplitSelector(selector string) (aggFn, table, column string) {
+	aggFn, rest, ok := strings.Cut(selector, "(")
+	if !ok {
+		return "", "", selector
+	}
+	rest = strings.TrimSuffix(rest, ")")
+	table, column, ok = strings.Cut(rest, ".")
+	if !ok {
+		return aggFn, "", rest
+	}
+	return aggFn, table, column
+}
+
+// ColumnInfos looks up the definition of each column of the query result given
+// by its selector. The columns of a table are retrieved once using describe.
+// Columns without a definition are reported as nil, e.g. aggregations,
+// computed values and columns of tables, which have been renamed using AS.
+func ColumnInfos(query string, selectors []string, describe func(table string) (map[string]ColumnInfo, error)) []*ColumnInfo {
+	// Selectors use the alias of a table, if one has been assigned,
+	// which must not be mistaken for a table with the same name.
+	queried := QueriedTables(query)
+	tables := make(map[string]map[string]ColumnInfo)
+	infos := make([]*ColumnInfo, len(selectors))
+	for i, selector := range selectors {
+		aggFn, table, column := SplitSelector(selector)
+		if aggFn != "" || !queried[table] {
+			continue
+		}
+		cols, ok := tables[table]
+		if !ok {
+			// A table, which cannot be described, is skipped.
+			cols, _ = describe(table)
+			tables[table] = cols
+		}
+		if info, ok := cols[column]; ok {
+			infos[i] = &info
+		}
+	}
+	return infos
+}
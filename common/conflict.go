@@ -0,0 +1,51 @@
+package common
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/codenotary/immudb/embedded/store"
+	immuerrors "github.com/codenotary/immudb/pkg/client/errors"
+	"google.golang.org/grpc/status"
+)
+
+// IsTxConflict checks if a transaction has been aborted, because data read by
+// it has been modified by another transaction committed in the meantime.
+func IsTxConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	// The embedded engine returns the error of the store.
+	if errors.Is(err, store.ErrTxReadConflict) {
+		return true
+	}
+	// The server only transfers the message of the error
+	// as part of the gRPC status returned to the client.
+	msg, remote := remoteMessage(err)
+	return remote && strings.Contains(msg, store.ErrTxReadConflict.Error())
+}
+
+// IsKeyNotFound checks if an entry requested from immudb does not exist.
+func IsKeyNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return true
+	}
+	// The server reports the error of the store as the message of the gRPC status.
+	msg, remote := remoteMessage(err)
+	return remote && msg == store.ErrKeyNotFound.Error()
+}
+
+// remoteMessage returns the message of an error received from the server.
+func remoteMessage(err error) (string, bool) {
+	var immuErr immuerrors.ImmuError
+	if errors.As(err, &immuErr) {
+		return immuErr.Error(), true
+	}
+	if st, ok := status.FromError(err); ok {
+		return st.Message(), true
+	}
+	return "", false
+}
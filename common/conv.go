@@ -2,6 +2,8 @@ package common
 
 import (
 	"database/sql/driver"
+	"encoding/json"
+	"math"
 	"reflect"
 	"strconv"
 	"time"
@@ -26,7 +28,8 @@ func NamedValueToMapString(args []driver.NamedValue) map[string]interface{} {
 }
 
 // ColumnTypeScanType returns the type of a go value into which a value of Type SQLValueType can be scanned.
-func ColumnTypeScanType(sqlValueType string) reflect.Type {
+// The type for UUID values depends on the format in which they are returned.
+func ColumnTypeScanType(sqlValueType string, uuidFormat UUIDFormat) reflect.Type {
 	switch sqlValueType {
 	case "INTEGER":
 		return reflect.TypeOf(int64(0))
@@ -38,11 +41,33 @@ func ColumnTypeScanType(sqlValueType string) reflect.Type {
 		return reflect.TypeOf([]byte{})
 	case "TIMESTAMP":
 		return reflect.TypeOf(time.Time{})
-	// These cases should not be reached.
-	// Nevertheless []byte should be safe default for scanning values.
+	case "FLOAT":
+		return reflect.TypeOf(float64(0))
+	case "UUID":
+		return UUIDScanType(uuidFormat)
+	case "JSON":
+		return reflect.TypeOf(json.RawMessage{})
+	// The type of values is unknown, e.g. for NULL literals.
 	case "ANY":
-		fallthrough
+		return reflect.TypeOf((*any)(nil)).Elem()
+	// This case should not be reached.
+	// Nevertheless []byte should be safe default for scanning values.
 	default:
 		return reflect.TypeOf([]byte{})
 	}
 }
+
+// ColumnTypeLength returns the length of a column of a variable length type.
+// The maximum length declared for VARCHAR and BLOB columns is taken from
+// their definition. If it is not limited or unknown, math.MaxInt64 is returned.
+func ColumnTypeLength(sqlValueType string, info *ColumnInfo) (length int64, ok bool) {
+	switch sqlValueType {
+	case "VARCHAR", "BLOB", "JSON":
+		if info != nil && info.MaxLen > 0 {
+			return int64(info.MaxLen), true
+		}
+		return math.MaxInt64, true
+	default:
+		return 0, false
+	}
+}
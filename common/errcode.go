@@ -0,0 +1,117 @@
+package common
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/embedded/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorCode identifies the kind of an error reported by immudb.
+// The values of the codes are stable and may be stored or compared.
+type ErrorCode string
+
+// Define the codes of errors reported by immudb.
+const (
+	// CodeUniqueViolation is used, if a row with the same primary key
+	// or values of a unique index already exists.
+	CodeUniqueViolation ErrorCode = "unique_violation"
+	// CodeTableNotFound is used, if a table does not exist.
+	CodeTableNotFound ErrorCode = "table_not_found"
+	// CodeColumnNotFound is used, if a column does not exist.
+	CodeColumnNotFound ErrorCode = "column_not_found"
+	// CodeTxConflict is used, if a transaction has been aborted,
+	// because another transaction modified the data read by it.
+	CodeTxConflict ErrorCode = "tx_conflict"
+	// CodeNotNullViolation is used, if NULL is assigned to a column,
+	// which is not nullable.
+	CodeNotNullViolation ErrorCode = "not_null_violation"
+	// CodeCheckViolation is used, if a row does not satisfy a check constraint.
+	CodeCheckViolation ErrorCode = "check_violation"
+	// CodeSyntaxError is used, if a query cannot be parsed.
+	CodeSyntaxError ErrorCode = "syntax_error"
+	// CodePermissionDenied is used, if the user is not allowed to execute a statement.
+	CodePermissionDenied ErrorCode = "permission_denied"
+	// CodeSessionExpired is used, if the session of a client connection
+	// is no longer known by the server, e.g. as it has been inactive for too long.
+	CodeSessionExpired ErrorCode = "session_expired"
+)
+
+// Error is an error reported by immudb together with a code identifying its kind.
+type Error struct {
+	Code ErrorCode
+	// Err is the error reported by immudb.
+	Err error
+}
+
+// NewError creates an error of the given kind.
+func NewError(code ErrorCode, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+// Error returns the message of the error reported by immudb.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return strings.ReplaceAll(string(e.Code), "_", " ")
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the error reported by immudb.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is checks if the target is an Error without a cause and with the same code.
+// It allows comparing errors with the kinds defined by errors.Is.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Err == nil && t.Code == e.Code
+}
+
+// errorCodes lists the errors of immudb belonging to each kind.
+var errorCodes = []struct {
+	code ErrorCode
+	errs []error
+}{
+	{CodeUniqueViolation, []error{store.ErrKeyAlreadyExists}},
+	{CodeTableNotFound, []error{sql.ErrTableDoesNotExist}},
+	{CodeColumnNotFound, []error{sql.ErrColumnDoesNotExist, sql.ErrInvalidColumn}},
+	{CodeNotNullViolation, []error{sql.ErrNotNullableColumnCannotBeNull, sql.ErrPKCanNotBeNull}},
+	{CodeCheckViolation, []error{sql.ErrCheckConstraintViolation}},
+	{CodeSyntaxError, []error{sql.ErrParsingError, errors.New("syntax error")}},
+	{CodePermissionDenied, []error{sql.ErrAccessDenied, errors.New("permission denied")}},
+	{CodeSessionExpired, []error{errors.New("session not found"), errors.New("no session found"), errors.New("not logged in")}},
+}
+
+// TranslateError wraps an error reported by immudb in an Error, if its kind is
+// known. Otherwise the error is returned unchanged. Errors of the embedded
+// engine are identified directly. The server only transfers the message of an
+// error to the client, which is therefore used to identify its kind.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var immuErr *Error
+	if errors.As(err, &immuErr) {
+		return err
+	}
+	if IsTxConflict(err) {
+		return NewError(CodeTxConflict, err)
+	}
+	msg, remote := remoteMessage(err)
+	for _, c := range errorCodes {
+		for _, target := range c.errs {
+			if errors.Is(err, target) || remote && strings.Contains(msg, target.Error()) {
+				return NewError(c.code, err)
+			}
+		}
+	}
+	if st, ok := status.FromError(err); ok && st.Code() == codes.PermissionDenied {
+		return NewError(CodePermissionDenied, err)
+	}
+	return err
+}
@@ -5,7 +5,20 @@ import "errors"
 var ErrNotImplemented = errors.New("the interface is not implemented")
 var ErrTxAlreadyFinished = errors.New("the transaction has already been finished")
 var ErrNestedTxNotSupported = errors.New("nested transactions are currently not supported")
-var ErrReadOnlyTxNotSupported = errors.New("immudb currently does not support read only transactions")
+var ErrReadOnlyTx = errors.New("statements modifying data cannot be executed in a read only transaction")
+
+// Deprecated: Read only transactions are supported now.
+// Statements modifying data in them fail with ErrReadOnlyTx.
+var ErrReadOnlyTxNotSupported = ErrReadOnlyTx
+
 var ErrIsolationLevelNotSupported = errors.New("immudb currently does not support the requested isolation level")
-var ErrConfigAlreadyRegistered = errors.New("an embedded engine configuration with this name already exists")
-var ErrNoConfigRegistered = errors.New("the named embedded configuration was not registered")
+var ErrConfigAlreadyRegistered = errors.New("a configuration with this name already exists")
+var ErrNoConfigRegistered = errors.New("the named configuration was not registered")
+var ErrVerificationFailed = errors.New("the verification of the retrieved data failed")
+var ErrPrimaryKeyMismatch = errors.New("the number of values does not match the primary key")
+var ErrNoResult = errors.New("no statement has been executed using the connection")
+var ErrUnsupportedParam = errors.New("the type of the parameter is not supported")
+var ErrParamOverflow = errors.New("the value of the parameter exceeds the range of INTEGER")
+var ErrInvalidIdentifier = errors.New("the name is not a valid identifier")
+var ErrInvalidSnapshot = errors.New("the snapshot defines neither a transaction nor a point in time")
+var ErrSnapshotPeriod = errors.New("a table read at a snapshot cannot have a period clause")
@@ -0,0 +1,16 @@
+package common
+
+import "time"
+
+// Revision is a version of a row stored by a transaction.
+type Revision struct {
+	// TxID is the id of the transaction, which stored the revision.
+	TxID uint64
+	// Timestamp is the time at which the transaction was committed.
+	Timestamp time.Time
+	// Deleted is set if the row was deleted by the transaction.
+	// The values of a deleted revision are those of the deleted row.
+	Deleted bool
+	// Values contains the value of each column of the row.
+	Values map[string]any
+}
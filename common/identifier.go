@@ -0,0 +1,22 @@
+package common
+
+import "fmt"
+
+// QuoteIdentifier validates the name of a table or column and encloses it
+// in double quotes, so that it can be embedded into the text of a statement,
+// even if it is a keyword. Immudb only permits names consisting of letters,
+// digits and underscores, which do not start with a digit.
+func QuoteIdentifier(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("%w: empty name", ErrInvalidIdentifier)
+	}
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		letter := 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+		digit := '0' <= ch && ch <= '9'
+		if !letter && !(digit && i > 0) {
+			return "", fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+		}
+	}
+	return `"` + name + `"`, nil
+}
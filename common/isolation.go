@@ -0,0 +1,34 @@
+package common
+
+import (
+	"database/sql"
+	"database/sql/driver"
+)
+
+// CheckIsolation checks if immudb provides the guarantees of an isolation level
+// of database/sql and reports if transactions using it are created with the
+// UnsafeMVCC option of immudb. Immudb reads from a snapshot, which must include
+// every transaction precommitted before the transaction started, and checks
+// during the commit if any data read or written by the transaction has been
+// modified by another one in the meantime. Therefore:
+//
+//   - LevelDefault, LevelRepeatableRead, LevelSnapshot and LevelSerializable
+//     use the default options of immudb transactions, which provide the full
+//     conflict detection of immudb.
+//   - LevelReadUncommitted and LevelReadCommitted use UnsafeMVCC. Conflicts
+//     are detected without waiting for the index to include the most recent
+//     transactions, so modifications of concurrent transactions may be missed.
+//   - LevelWriteCommitted and LevelLinearizable are rejected with
+//     ErrIsolationLevelNotSupported. Immudb has no mode only isolating writes
+//     and a snapshot may include transactions, which have been precommitted,
+//     but are not yet durably committed.
+func CheckIsolation(level driver.IsolationLevel) (unsafeMVCC bool, err error) {
+	switch sql.IsolationLevel(level) {
+	case sql.LevelDefault, sql.LevelRepeatableRead, sql.LevelSnapshot, sql.LevelSerializable:
+		return false, nil
+	case sql.LevelReadUncommitted, sql.LevelReadCommitted:
+		return true, nil
+	default:
+		return false, ErrIsolationLevelNotSupported
+	}
+}
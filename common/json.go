@@ -0,0 +1,45 @@
+package common
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// jsonParam returns the JSON text of v, if it is a json.RawMessage, a value
+// implementing json.Marshaler, a map or a slice or array, whose elements are
+// not bytes. It reports false for all other values including those
+// implementing driver.Valuer and times, which are converted by ConvertParam.
+// Nil maps and slices are reported with a nil text, as they are stored as NULL.
+func jsonParam(v any) (any, bool, error) {
+	switch v := v.(type) {
+	case nil, driver.Valuer, time.Time:
+		return nil, false, nil
+	case json.RawMessage:
+		return string(v), true, nil
+	case json.Marshaler:
+		data, err := v.MarshalJSON()
+		return string(data), true, err
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		// Bytes are stored as BLOB values instead.
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false, nil
+		}
+	case reflect.Map:
+	default:
+		return nil, false, nil
+	}
+	if (rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice) && rv.IsNil() {
+		return nil, true, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %w", ErrUnsupportedParam, err)
+	}
+	return string(data), true, nil
+}
@@ -0,0 +1,161 @@
+package common
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// LogLevel defines the logging level to be used by a logger.
+type LogLevel int
+
+// Define log levels. The default level is debug.
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+	LogLevelFatal
+	LogLevelPanic
+	LogLevelNone
+
+	LogLevelTrace LogLevel = -1
+)
+
+// Logger is the interface for logging debug messages of the driver.
+// The data contains additional structured information about the event.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, data map[string]any)
+}
+
+// Log writes a message to the logger, if a logger has been set.
+func Log(ctx context.Context, logger Logger, level LogLevel, msg string, data map[string]any) {
+	if logger == nil {
+		return
+	}
+	logger.Log(ctx, level, msg, data)
+}
+
+// LogArgsMode defines how the arguments of a query are logged.
+type LogArgsMode int
+
+const (
+	// LogArgsOmit omits the argument values from the log messages.
+	LogArgsOmit LogArgsMode = iota
+	// LogArgsHash logs a keyed hash of each argument value instead of the value.
+	LogArgsHash
+	// LogArgsTruncate logs only the beginning of each argument value.
+	LogArgsTruncate
+)
+
+// defaultTruncateLength is the number of characters kept
+// of truncated argument values if no length has been set.
+const defaultTruncateLength = 8
+
+// LogPolicy defines which information about queries is logged.
+// The zero value logs the sql text of all queries
+// at debug level without any argument values.
+type LogPolicy struct {
+	// SlowQueryThreshold is the duration after which a query is considered slow.
+	// Slow queries are logged at warn level. Zero disables the detection.
+	SlowQueryThreshold time.Duration
+	// Args defines how the argument values of a query are logged.
+	Args LogArgsMode
+	// TruncateLength is the number of characters kept of argument values
+	// for LogArgsTruncate. Without a value 8 characters are kept.
+	TruncateLength int
+	// HashKey is the key of the HMAC used for LogArgsHash. Without a key,
+	// a random key is generated once per process. Hashes of equal values
+	// can then only be correlated within the logs of a single process.
+	HashKey []byte
+}
+
+// processHashKey is the key used for hashing arguments without a HashKey.
+var processHashKey = func() []byte {
+	key := make([]byte, sha256.Size)
+	// rand.Read never returns an error.
+	_, _ = rand.Read(key)
+	return key
+}()
+
+// noQueryLogKey is the context key for disabling the query log.
+type noQueryLogKey struct{}
+
+// WithoutQueryLog returns a context for which queries are not logged.
+func WithoutQueryLog(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noQueryLogKey{}, true)
+}
+
+// QueryLogDisabled checks if queries executed with ctx must not be logged.
+func QueryLogDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noQueryLogKey{}).(bool)
+	return disabled
+}
+
+// LogQuery logs the execution of a query, which was started at start.
+// The policy defines the level of the message and how the arguments are logged.
+// If the execution failed, the error is logged instead.
+func LogQuery(ctx context.Context, logger Logger, policy LogPolicy, msg string, query string, args []driver.NamedValue, start time.Time, err error) {
+	if logger == nil || QueryLogDisabled(ctx) {
+		return
+	}
+	duration := time.Since(start)
+	data := map[string]any{
+		"sql":      query,
+		"duration": duration,
+	}
+	if policy.Args != LogArgsOmit && len(args) > 0 {
+		data["args"] = policy.formatArgs(args)
+	}
+	level := LogLevelDebug
+	switch {
+	case err != nil:
+		data["err"] = err
+		level = LogLevelError
+	case policy.SlowQueryThreshold > 0 && duration >= policy.SlowQueryThreshold:
+		data["slow"] = true
+		level = LogLevelWarn
+	}
+	logger.Log(ctx, level, msg, data)
+}
+
+// formatArgs converts the argument values into their logged representation.
+func (policy LogPolicy) formatArgs(args []driver.NamedValue) []string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		value := fmt.Sprint(arg.Value)
+		if arg.Value == nil {
+			value = "NULL"
+		}
+		switch policy.Args {
+		case LogArgsHash:
+			// An unkeyed hash of a value with low entropy, e.g. a name,
+			// could be reversed by hashing all candidate values.
+			key := policy.HashKey
+			if len(key) == 0 {
+				key = processHashKey
+			}
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(value))
+			value = hex.EncodeToString(mac.Sum(nil)[:8])
+		case LogArgsTruncate:
+			length := policy.TruncateLength
+			if length <= 0 {
+				length = defaultTruncateLength
+			}
+			if runes := []rune(value); len(runes) > length {
+				value = string(runes[:length]) + "..."
+			}
+		}
+		if arg.Name != "" {
+			value = arg.Name + "=" + value
+		}
+		res[i] = value
+	}
+	return res
+}
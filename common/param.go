@@ -0,0 +1,118 @@
+package common
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// CheckNamedValue converts a parameter of a statement using ConvertParam.
+// It is used by both backends to implement driver.NamedValueChecker,
+// so that parameters are passed to immudb in the same way.
+func CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := ConvertParam(nv.Value)
+	if err != nil {
+		name := nv.Name
+		if name == "" {
+			name = "$" + strconv.Itoa(nv.Ordinal)
+		}
+		return fmt.Errorf("converting parameter %s failed: %w", name, err)
+	}
+	nv.Value = value
+	return nil
+}
+
+// ConvertParam converts the value of a parameter into nil or a value of the
+// types int64, float64, bool, string, []byte or time.Time, which are
+// supported by the embedded engine as well as the client.
+//
+//   - Values implementing driver.Valuer are converted using their value.
+//   - Pointers are dereferenced, nil pointers are converted to nil.
+//   - Signed and unsigned integers are converted to int64. Unsigned integers
+//     exceeding the range of int64 are rejected with ErrParamOverflow.
+//   - Times are converted to UTC with a precision of microseconds.
+//   - Byte arrays like [16]byte are converted to byte slices. A uuid.UUID
+//     is converted to its string representation, as it is a driver.Valuer.
+//   - json.RawMessage, values implementing json.Marshaler, maps and slices
+//     or arrays, whose elements are not bytes, are converted to their JSON
+//     text. Nil maps and slices are converted to nil.
+//
+// All other values are rejected with ErrUnsupportedParam.
+func ConvertParam(v any) (any, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		// A nil pointer to a type implementing driver.Valuer
+		// using a value receiver is treated as NULL like database/sql does.
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() && rv.Type().Elem().Implements(valuerType) {
+			return nil, nil
+		}
+		value, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		return convertValue(value)
+	}
+	return convertValue(v)
+}
+
+// valuerType is the type of the driver.Valuer interface.
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// convertValue converts a value, which does not implement driver.Valuer.
+func convertValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, int64, float64, bool, string, []byte:
+		return v, nil
+	case time.Time:
+		return v.UTC().Truncate(time.Microsecond), nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		// MarshalJSON may only be implemented using a pointer receiver.
+		elem := rv.Elem().Interface()
+		if _, ok := elem.(json.Marshaler); !ok {
+			if _, ok := v.(json.Marshaler); ok {
+				text, _, err := jsonParam(v)
+				return text, err
+			}
+		}
+		return ConvertParam(elem)
+	}
+	if text, ok, err := jsonParam(v); ok || err != nil {
+		return text, err
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %d", ErrParamOverflow, u)
+		}
+		return int64(u), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(data), rv)
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedParam, v)
+}
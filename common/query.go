@@ -0,0 +1,147 @@
+package common
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a token in an sql query.
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenLiteral
+	tokenComment
+	tokenSpace
+	tokenOther
+)
+
+// token is a part of an sql query.
+type token struct {
+	kind tokenKind
+	text string
+	// end is the position in the query after the token.
+	end int
+}
+
+// tokenize splits a query into words, literals, comments, white space and other characters.
+// It only recognizes as much of the syntax as required to find keywords and identifiers.
+func tokenize(query string) []token {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		kind := tokenOther
+		end := i + 1
+		switch {
+		case c == '\'' || c == '"':
+			kind = tokenLiteral
+			for end < len(query) {
+				if query[end] == c {
+					// Quotes are escaped by doubling them.
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(query))
+		case strings.HasPrefix(query[i:], "--"):
+			kind = tokenComment
+			end = strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			kind = tokenComment
+			end = strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+		case isIdentifierChar(c):
+			kind = tokenWord
+			for end < len(query) && isIdentifierChar(query[end]) {
+				end++
+			}
+		case unicode.IsSpace(rune(c)):
+			kind = tokenSpace
+		}
+		tokens = append(tokens, token{kind: kind, text: query[i:end], end: end})
+		i = end
+	}
+	return tokens
+}
+
+// QueriedTables returns the tables read by the query, which can be referenced
+// by their own name. Tables renamed using an alias and aliases, which have the
+// same name as a table, are excluded. Aliases following a period clause are
+// not recognized. Like immudb does, the names are converted to lower case.
+func QueriedTables(query string) map[string]bool {
+	tables := make(map[string]bool)
+	aliases := make(map[string]bool)
+	// table is the name of the table read last, while its alias may follow.
+	table := ""
+	// tableNext is set after FROM and JOIN, as they are followed by a table.
+	// aliasNext is set after AS, if it follows a table.
+	tableNext, aliasNext := false, false
+	for _, t := range tokenize(query) {
+		if t.kind == tokenSpace || t.kind == tokenComment {
+			continue
+		}
+		name, isName := identifier(t)
+		switch {
+		case tableNext && isName:
+			table = name
+			tables[name] = true
+			tableNext = false
+			continue
+		case table != "" && !aliasNext && t.kind == tokenWord && strings.EqualFold(t.text, "AS"):
+			aliasNext = true
+			continue
+		case table != "" && isName && (aliasNext || !isClauseKeyword(name)):
+			// The alias may also follow a table without AS.
+			if name != table {
+				aliases[name] = true
+			}
+		}
+		table, aliasNext = "", false
+		tableNext = t.kind == tokenWord && (strings.EqualFold(t.text, "FROM") || strings.EqualFold(t.text, "JOIN"))
+	}
+	for name := range aliases {
+		delete(tables, name)
+	}
+	return tables
+}
+
+// identifier returns the lower case name of an identifier,
+// which may be enclosed in double quotes.
+func identifier(t token) (string, bool) {
+	switch {
+	case t.kind == tokenWord:
+		return strings.ToLower(t.text), true
+	case t.kind == tokenLiteral && strings.HasPrefix(t.text, `"`):
+		return strings.ToLower(strings.Trim(t.text, `"`)), true
+	}
+	return "", false
+}
+
+// isClauseKeyword checks if the lower case word starts
+// a clause, which may follow a table in a query.
+func isClauseKeyword(word string) bool {
+	switch word {
+	case "where", "join", "inner", "left", "right", "on", "group", "having", "order",
+		"limit", "offset", "union", "use", "since", "after", "until", "before":
+		return true
+	}
+	return false
+}
+
+// isIdentifierChar checks if c can be part of an identifier or keyword.
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
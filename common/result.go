@@ -0,0 +1,75 @@
+package common
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// Result provides the metadata of the transactions committed
+// by executing a statement or by committing a transaction.
+type Result interface {
+	// TxIDs returns the ids of the committed transactions.
+	TxIDs() []uint64
+	// Timestamps returns the time at which each transaction was committed.
+	Timestamps() []time.Time
+	// Hashes returns the accumulative linear hash of each transaction,
+	// which immudb uses to prove the consistency of the database.
+	Hashes() [][sha256.Size]byte
+	// LastInsertedPKs returns the last value assigned to the auto increment
+	// primary key of each table by the statement or the committed transaction.
+	// Statements executed as part of a transaction using an embedded engine
+	// only report their own keys. Using a client connection, they report no
+	// keys, as the server does not report them before the transaction is
+	// committed.
+	LastInsertedPKs() map[string]int64
+}
+
+// TxInfo is the metadata of a committed transaction.
+type TxInfo struct {
+	ID              uint64
+	Timestamp       time.Time
+	Hash            [sha256.Size]byte
+	LastInsertedPKs map[string]int64
+}
+
+// TxResult is the Result of a list of committed transactions.
+type TxResult []TxInfo
+
+// TxIDs returns the ids of the committed transactions.
+func (r TxResult) TxIDs() []uint64 {
+	ids := make([]uint64, len(r))
+	for i, tx := range r {
+		ids[i] = tx.ID
+	}
+	return ids
+}
+
+// Timestamps returns the time at which each transaction was committed.
+func (r TxResult) Timestamps() []time.Time {
+	timestamps := make([]time.Time, len(r))
+	for i, tx := range r {
+		timestamps[i] = tx.Timestamp
+	}
+	return timestamps
+}
+
+// Hashes returns the accumulative linear hash of each transaction.
+func (r TxResult) Hashes() [][sha256.Size]byte {
+	hashes := make([][sha256.Size]byte, len(r))
+	for i, tx := range r {
+		hashes[i] = tx.Hash
+	}
+	return hashes
+}
+
+// LastInsertedPKs returns the last value assigned to the
+// auto increment primary key of each table by the transactions.
+func (r TxResult) LastInsertedPKs() map[string]int64 {
+	pks := map[string]int64{}
+	for _, tx := range r {
+		for table, pk := range tx.LastInsertedPKs {
+			pks[table] = pk
+		}
+	}
+	return pks
+}
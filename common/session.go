@@ -0,0 +1,45 @@
+package common
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unavailableMessages contains the messages of errors raised by the client
+// or gRPC, if the server cannot be reached. The client only keeps the message
+// of a gRPC status, hence the errors are identified using it.
+var unavailableMessages = []string{
+	"not connected",
+	"connection error",
+	"error reading from server",
+	"transport is closing",
+}
+
+// IsSessionExpired checks if the server no longer knows the session
+// of a client connection, e.g. as it exceeded its maximum age.
+func IsSessionExpired(err error) bool {
+	return errors.Is(TranslateError(err), &Error{Code: CodeSessionExpired})
+}
+
+// IsUnavailable checks if a request failed, because the server could not be reached.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if st, ok := status.FromError(err); ok && st.Code() == codes.Unavailable {
+		return true
+	}
+	msg, remote := remoteMessage(err)
+	if !remote {
+		return false
+	}
+	for _, m := range unavailableMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Snapshot defines the state of the database, which is read by a query.
+// Either the id of a transaction or a point in time is set.
+type Snapshot struct {
+	TxID uint64
+	Time time.Time
+}
+
+// snapshotKey is the context key for the snapshot of queries.
+type snapshotKey struct{}
+
+// WithSnapshot returns a context for which queries read the given snapshot.
+func WithSnapshot(ctx context.Context, snapshot Snapshot) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, snapshot)
+}
+
+// SnapshotFrom retrieves the snapshot stored in the context.
+func SnapshotFrom(ctx context.Context) (Snapshot, bool) {
+	snapshot, ok := ctx.Value(snapshotKey{}).(Snapshot)
+	return snapshot, ok
+}
+
+// period returns the period clause of immudb selecting the snapshot.
+func (s Snapshot) period() string {
+	if s.TxID > 0 {
+		return "UNTIL TX " + strconv.FormatUint(s.TxID, 10)
+	}
+	return "UNTIL '" + s.Time.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+}
+
+// Validate checks that the snapshot defines a transaction or a point in time.
+func (s Snapshot) Validate() error {
+	if s.TxID == 0 && s.Time.IsZero() {
+		return ErrInvalidSnapshot
+	}
+	return nil
+}
+
+// ApplySnapshot rewrites the query to read the tables as they were
+// at the time of the snapshot stored in the context. It adds a period
+// clause to every table referenced after FROM or JOIN. Tables, which
+// already have a period clause, are rejected, as it would conflict with
+// the snapshot. Without a snapshot the query is returned unchanged.
+//
+// The snapshot options of immudb transactions only ensure that a snapshot
+// includes at least a transaction. Reading the state of a table up to
+// a transaction additionally requires a period clause.
+func ApplySnapshot(ctx context.Context, query string) (string, error) {
+	snapshot, ok := SnapshotFrom(ctx)
+	if !ok {
+		return query, nil
+	}
+	if err := snapshot.Validate(); err != nil {
+		return "", err
+	}
+	period := snapshot.period()
+	var res strings.Builder
+	// tableNext is set after FROM and JOIN, as they are followed by a table.
+	tableNext := false
+	for _, t := range tokenize(query) {
+		res.WriteString(t.text)
+		switch t.kind {
+		case tokenWord:
+			if tableNext {
+				if err := addPeriod(&res, query[t.end:], period); err != nil {
+					return "", err
+				}
+			}
+			tableNext = strings.EqualFold(t.text, "FROM") || strings.EqualFold(t.text, "JOIN")
+		case tokenLiteral:
+			// Identifiers may be enclosed in double quotes.
+			if tableNext && strings.HasPrefix(t.text, `"`) {
+				if err := addPeriod(&res, query[t.end:], period); err != nil {
+					return "", err
+				}
+			}
+			tableNext = false
+		case tokenSpace, tokenComment:
+		default:
+			// A literal, a subquery or any other expression follows.
+			tableNext = false
+		}
+	}
+	return res.String(), nil
+}
+
+// addPeriod appends the period clause to the table, which is followed
+// by the rest of the query. Function calls like TABLES() are skipped.
+func addPeriod(res *strings.Builder, rest string, period string) error {
+	rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	if strings.HasPrefix(rest, "(") {
+		return nil
+	}
+	end := 0
+	for end < len(rest) && isIdentifierChar(rest[end]) {
+		end++
+	}
+	switch strings.ToUpper(rest[:end]) {
+	case "SINCE", "AFTER", "UNTIL", "BEFORE":
+		return ErrSnapshotPeriod
+	}
+	res.WriteString(" " + period)
+	return nil
+}
@@ -0,0 +1,18 @@
+package common
+
+import "github.com/codenotary/immudb/embedded/sql"
+
+// ReadOnly checks if none of the statements modifies data. Like immudb,
+// it considers queries and statements controlling the transaction as read only.
+func ReadOnly(stmts []sql.SQLStmt) bool {
+	for _, stmt := range stmts {
+		switch stmt.(type) {
+		case *sql.SelectStmt, *sql.UnionStmt,
+			*sql.BeginTransactionStmt, *sql.CommitStmt, *sql.RollbackStmt,
+			*sql.UseDatabaseStmt, *sql.UseSnapshotStmt:
+		default:
+			return false
+		}
+	}
+	return true
+}
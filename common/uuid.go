@@ -0,0 +1,88 @@
+package common
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+)
+
+// UUIDFormat defines how the values of UUID columns are returned.
+type UUIDFormat int
+
+// Define the formats for returning UUID values. The default is UUIDFormatString.
+const (
+	// UUIDFormatString returns UUIDs using their string representation.
+	UUIDFormatString UUIDFormat = iota
+	// UUIDFormatBytes returns UUIDs as a byte slice of length 16.
+	UUIDFormatBytes
+	// UUIDFormatNative returns UUIDs as a byte slice of length 16
+	// and reports uuid.UUID as the type into which they are scanned.
+	UUIDFormatNative
+)
+
+// uuidFormatNames contains the names of the formats used in a dsn.
+var uuidFormatNames = map[UUIDFormat]string{
+	UUIDFormatString: "string",
+	UUIDFormatBytes:  "bytes",
+	UUIDFormatNative: "native",
+}
+
+// String returns the name of the format.
+func (f UUIDFormat) String() string {
+	if name, ok := uuidFormatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("UUIDFormat(%d)", int(f))
+}
+
+// ParseUUIDFormat returns the format with the given name.
+func ParseUUIDFormat(name string) (UUIDFormat, bool) {
+	for format, formatName := range uuidFormatNames {
+		if formatName == name {
+			return format, true
+		}
+	}
+	return UUIDFormatString, false
+}
+
+// UUIDValue converts a uuid read from immudb into the given format.
+// The embedded engine provides uuids as uuid.UUID,
+// while the server transfers them as strings.
+func UUIDValue(v any, format UUIDFormat) (driver.Value, error) {
+	var u uuid.UUID
+	switch v := v.(type) {
+	case uuid.UUID:
+		u = v
+	case string:
+		var err error
+		u, err = uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("parsing uuid '%s' failed: %w", v, err)
+		}
+	default:
+		return nil, fmt.Errorf("converting %T to uuid is not supported", v)
+	}
+	switch format {
+	case UUIDFormatBytes, UUIDFormatNative:
+		// A uuid.UUID is not a valid driver.Value, so native
+		// uuids are returned as bytes, which it can scan.
+		return u[:], nil
+	default:
+		return u.String(), nil
+	}
+}
+
+// UUIDScanType returns the type of a go value into which a UUID
+// returned in the given format can be scanned.
+func UUIDScanType(format UUIDFormat) reflect.Type {
+	switch format {
+	case UUIDFormatBytes:
+		return reflect.TypeOf([]byte{})
+	case UUIDFormatNative:
+		return reflect.TypeOf(uuid.UUID{})
+	default:
+		return reflect.TypeOf("")
+	}
+}
@@ -1,13 +1,31 @@
 package immusql
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/appendable"
+	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/client"
 	"github.com/tauu/immusql/common"
 )
 
-// dsnConfig contains the connection parameters
-// parsed from a dsn string.
-type dsnConfig struct {
+// Default values used for parameters, which are not specified.
+const (
+	defaultHost     = "localhost"
+	defaultPort     = 3322
+	defaultDatabase = "defaultdb"
+)
+
+// Config contains the parameters for connecting to an immudb instance
+// or opening an embedded engine. It can be parsed from a dsn string using
+// ParseDSN and converted back using FormatDSN, which only retains the
+// fields that can be represented in a dsn.
+type Config struct {
 	User     string
 	Pass     string
 	Host     string
@@ -15,14 +33,138 @@ type dsnConfig struct {
 	Path     string
 	Name     string
 	Embedded bool
+	// TLS indicates that the connection to the server is secured using mTLS.
+	TLS bool
+	// TLSName is the name of the registered mTLS configuration
+	// used for the connection.
+	TLSName string
+	// DialTimeout limits the time for establishing a client connection.
+	DialTimeout time.Duration
+	// HealthCheckRetries is the number of health check retries of the client.
+	HealthCheckRetries int
+	// HeartBeatFrequency is the interval between session heartbeats.
+	// The heartbeats renew the session of a client connection, also while it
+	// is idle in the pool. The server closes sessions, which have not been
+	// renewed for longer than its session timeout. Connections with such an
+	// expired session are discarded, before they are reused.
+	HeartBeatFrequency time.Duration
+	// MaxRecvMsgSize is the maximum size of a received gRPC message.
+	MaxRecvMsgSize int
+	// TokenFile is the name of the file storing the client token.
+	TokenFile string
+	// StateDir is the directory in which the client persists the server state.
+	StateDir string
+	// DisableIdentityCheck disables validating the identity of the server.
+	DisableIdentityCheck bool
+	// ReadOnly only permits statements of an embedded engine,
+	// which do not modify any data.
+	ReadOnly bool
+	// Async disables syncing data to disk during each commit of an embedded engine.
+	Async bool
+	// SyncFrequency is the interval between syncs of an embedded engine.
+	SyncFrequency time.Duration
+	// MaxConcurrency is the maximum number of simultaneous commits.
+	MaxConcurrency int
+	// MaxIOConcurrency is the maximum number of simultaneous IO writes.
+	MaxIOConcurrency int
+	// FileSize is the maximum size of each file of the store.
+	FileSize int
+	// CompressionFormat is the format used to compress stored values.
+	CompressionFormat string
+	// CompressionLevel is the level used to compress stored values.
+	CompressionLevel int
+	// TxLogCacheSize is the size of the cache for transaction logs.
+	TxLogCacheSize int
+	// VLogCacheSize is the size of the cache for value logs.
+	VLogCacheSize int
+	// IndexCacheSize is the size of the index node cache in bytes.
+	IndexCacheSize int
+	// IndexFlushThreshold is the number of index entries between flushes.
+	IndexFlushThreshold int
+	// IndexSyncThreshold is the number of index entries between synced flushes.
+	IndexSyncThreshold int
+	// SortBufferSize is the size of the buffer used for sorting query results.
+	SortBufferSize int
+	// DistinctLimit is the maximum number of rows a DISTINCT query may process.
+	DistinctLimit int
+	// UUIDFormat is the format in which the values of UUID columns are returned.
+	UUIDFormat UUIDFormat
+
+	// The following fields cannot be represented in a dsn.
+
+	// ClientOptions are the base options for client connections.
+	// The other fields of the configuration are applied on top of them.
+	ClientOptions *client.Options
+	// TLSConfig secures client connections, even if TLS is not set.
+	// If it is set, no registered mTLS configuration is used.
+	TLSConfig *tls.Config
+	// DialContext establishes the network connection to the server.
+	// The immudb client ignores it for connections secured
+	// with a registered mTLS configuration.
+	DialContext func(ctx context.Context, addr string) (net.Conn, error)
+	// Logger receives the log messages of the driver.
+	Logger Logger
+	// LogPolicy defines which information about queries is logged.
+	LogPolicy LogPolicy
+	// StoreOptions are the base options for the store of an embedded engine.
+	// The other fields of the configuration are applied on top of them.
+	StoreOptions *store.Options
+}
+
+// UUIDFormat defines how the values of UUID columns are returned.
+//
+// Values of all formats can be scanned into a uuid.UUID using its Scan method.
+// The native format returns the same values as the bytes format, but reports
+// uuid.UUID as the scan type of UUID columns.
+type UUIDFormat = common.UUIDFormat
+
+// Define the formats for returning UUID values.
+const (
+	UUIDFormatString = common.UUIDFormatString
+	UUIDFormatBytes  = common.UUIDFormatBytes
+	UUIDFormatNative = common.UUIDFormatNative
+)
+
+// checkTLS verifies that the configuration for securing
+// client connections is available.
+func (cfg *Config) checkTLS() error {
+	// Nothing to check, if the connection is not secured
+	// or a tls configuration has been provided directly.
+	if !cfg.TLS || cfg.TLSConfig != nil {
+		return nil
+	}
+	// If no registered configuration is named explicitly,
+	// the one registered for the host of the server is used.
+	if cfg.TLSName == "" {
+		cfg.TLSName = cfg.Host
+	}
+	_, err := lookupTLSoptions(cfg.TLSName)
+	return err
+}
+
+// compressionFormats maps the names of compression formats
+// accepted in a dsn to the formats of the immudb store.
+var compressionFormats = map[string]int{
+	"none":  appendable.NoCompression,
+	"flate": appendable.FlateCompression,
+	"gzip":  appendable.GZipCompression,
+	"lzw":   appendable.LZWCompression,
+	"zlib":  appendable.ZLibCompression,
 }
 
 // mtlsOptions stores registered mtlsOptions
 // for ImmuDB client connections.
 var mtlsOptions map[string]client.MTLsOptions
 
+// mtlsOptionsLock guards access to mtlsOptions.
+var mtlsOptionsLock sync.RWMutex
+
 // RegisterTLSoptions registers a tls configuration to be sued for a specific domain name.
+// The configuration is used by connections with the immudbs scheme,
+// whose host is the domain name or which select it using the tls parameter.
 func RegisterTLSoptions(dns string, options client.MTLsOptions) error {
+	mtlsOptionsLock.Lock()
+	defer mtlsOptionsLock.Unlock()
 	// Create map if it does not exist.
 	if mtlsOptions == nil {
 		mtlsOptions = make(map[string]client.MTLsOptions)
@@ -35,3 +177,14 @@ func RegisterTLSoptions(dns string, options client.MTLsOptions) error {
 	mtlsOptions[dns] = options
 	return nil
 }
+
+// lookupTLSoptions retrieves a registered tls configuration.
+func lookupTLSoptions(name string) (client.MTLsOptions, error) {
+	mtlsOptionsLock.RLock()
+	defer mtlsOptionsLock.RUnlock()
+	options, ok := mtlsOptions[name]
+	if !ok {
+		return options, fmt.Errorf("%w: tls configuration %s", common.ErrNoConfigRegistered, name)
+	}
+	return options, nil
+}
@@ -3,22 +3,63 @@ package immusql
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
+	"sync"
 
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/embedded/store"
 	"github.com/codenotary/immudb/pkg/client"
 	driverClient "github.com/tauu/immusql/client"
+	"github.com/tauu/immusql/common"
 	"github.com/tauu/immusql/embedded"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // connector opens connections to a preconfigured immudb instance.
 type connector struct {
-	config dsnConfig
+	config Config
 	driver ImmudbDriver
+	// embeddedRef keeps the store of an embedded engine open,
+	// until the connector is closed.
+	embeddedRef *embedded.Reference
+	// transient is set for connectors, which only open a single connection.
+	// They do not keep the store of an embedded engine open.
+	transient bool
+	lock      sync.Mutex
+}
+
+// NewConnector creates a connector for the given configuration,
+// which can be used to open a database using sql.OpenDB.
+func NewConnector(config Config) (driver.Connector, error) {
+	// Use default values for all missing connection parameters.
+	if config.Host == "" {
+		config.Host = defaultHost
+	}
+	if config.Port == 0 {
+		config.Port = defaultPort
+	}
+	if config.Name == "" {
+		config.Name = defaultDatabase
+	}
+	if config.Embedded && config.Path == "" {
+		return nil, errors.New("the path of an embedded engine has to be specified")
+	}
+	// Verify that the mTLS configuration for secured connections is registered.
+	if err := config.checkTLS(); err != nil {
+		return nil, err
+	}
+	return &connector{config: config}, nil
 }
 
 // -- Connector interface --
 
 // Connect establishes a new connection to an immudb instance.
 func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	// Abort if the context has already been cancelled.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if c.config.Embedded {
 		return c.openEmbedded(ctx)
 	}
@@ -26,15 +67,37 @@ func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
 }
 
 // Driver returns the driver used by the connector.
-func (c *connector) Driver() *ImmudbDriver {
+func (c *connector) Driver() driver.Driver {
 	return &c.driver
 }
 
+// -- io.Closer interface --
+
+// Close releases the resources held by the connector.
+// It is called by database/sql when the database is closed.
+func (c *connector) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// Release the store of an embedded engine.
+	// It is closed as soon as all connections using it are closed as well.
+	if c.embeddedRef == nil {
+		return nil
+	}
+	err := c.embeddedRef.Close()
+	c.embeddedRef = nil
+	return err
+}
+
 // openClient opens an immuclient connection to an immudb.
 func (c *connector) openClient(ctx context.Context) (driver.Conn, error) {
 	// Assemble options for connecting to immudb.
-	options := client.DefaultOptions().
-		WithAddress(c.config.Host).
+	// The base options are copied, as the client modifies them.
+	options := client.DefaultOptions()
+	if c.config.ClientOptions != nil {
+		baseOptions := *c.config.ClientOptions
+		options = &baseOptions
+	}
+	options = options.WithAddress(c.config.Host).
 		WithPort(c.config.Port).
 		WithDatabase(c.config.Name)
 	// Set username and password.
@@ -42,11 +105,157 @@ func (c *connector) openClient(ctx context.Context) (driver.Conn, error) {
 		options = options.WithUsername(c.config.User).
 			WithPassword(c.config.Pass)
 	}
-	return driverClient.Open(ctx, options)
+	// Secure the connection using the provided tls configuration
+	// or the registered mTLS configuration.
+	// The dial options are copied, as they are extended below.
+	dialOptions := append([]grpc.DialOption{}, options.DialOptions...)
+	if c.config.TLSConfig != nil {
+		creds := credentials.NewTLS(c.config.TLSConfig)
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(creds))
+	} else if c.config.TLS {
+		mtls, err := lookupTLSoptions(c.config.TLSName)
+		if err != nil {
+			return nil, err
+		}
+		options = options.WithMTLs(true).
+			WithMTLsOptions(mtls)
+	}
+	// Establish the network connection using a custom dial function.
+	if c.config.DialContext != nil {
+		dialOptions = append(dialOptions, grpc.WithContextDialer(c.config.DialContext))
+	}
+	options = options.WithDialOptions(dialOptions)
+	// Apply the options set by the parameters of the dsn.
+	if c.config.HealthCheckRetries > 0 {
+		options = options.WithHealthCheckRetries(c.config.HealthCheckRetries)
+	}
+	if c.config.HeartBeatFrequency > 0 {
+		options = options.WithHeartBeatFrequency(c.config.HeartBeatFrequency)
+	}
+	if c.config.MaxRecvMsgSize > 0 {
+		options = options.WithMaxRecvMsgSize(c.config.MaxRecvMsgSize)
+	}
+	if c.config.TokenFile != "" {
+		options = options.WithTokenFileName(c.config.TokenFile)
+	}
+	if c.config.StateDir != "" {
+		options = options.WithDir(c.config.StateDir)
+	}
+	if c.config.DisableIdentityCheck {
+		options = options.WithDisableIdentityCheck(true)
+	}
+	// Limit the time for establishing the connection.
+	if c.config.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.DialTimeout)
+		defer cancel()
+	}
+	return driverClient.Open(ctx, driverClient.Options{
+		Client:     options,
+		UUIDFormat: c.config.UUIDFormat,
+		Logger:     c.logger(),
+		Policy:     c.config.LogPolicy,
+	})
 }
 
 // openEmbedded creates an embedded immudb engine.
 func (c *connector) openEmbedded(ctx context.Context) (driver.Conn, error) {
+	// Assemble options for the store of the engine.
+	// The base options are copied, as they are modified below.
+	storeOpts := store.DefaultOptions()
+	if c.config.StoreOptions != nil {
+		baseOpts := *c.config.StoreOptions
+		storeOpts = &baseOpts
+	}
+	// The sql engine requires multi indexing.
+	storeOpts = storeOpts.WithMultiIndexing(true)
+	if c.config.Async {
+		storeOpts = storeOpts.WithSynced(false)
+	}
+	if c.config.SyncFrequency > 0 {
+		storeOpts = storeOpts.WithSyncFrequency(c.config.SyncFrequency)
+	}
+	if c.config.MaxConcurrency > 0 {
+		storeOpts = storeOpts.WithMaxConcurrency(c.config.MaxConcurrency)
+	}
+	if c.config.MaxIOConcurrency > 0 {
+		storeOpts = storeOpts.WithMaxIOConcurrency(c.config.MaxIOConcurrency)
+	}
+	if c.config.FileSize > 0 {
+		storeOpts = storeOpts.WithFileSize(c.config.FileSize)
+	}
+	if c.config.CompressionFormat != "" {
+		storeOpts = storeOpts.WithCompressionFormat(compressionFormats[c.config.CompressionFormat])
+	}
+	if c.config.CompressionLevel > 0 {
+		storeOpts = storeOpts.WithCompresionLevel(c.config.CompressionLevel)
+	}
+	if c.config.TxLogCacheSize > 0 {
+		storeOpts = storeOpts.WithTxLogCacheSize(c.config.TxLogCacheSize)
+	}
+	if c.config.VLogCacheSize > 0 {
+		storeOpts = storeOpts.WithVLogCacheSize(c.config.VLogCacheSize)
+	}
+	// Assemble options for the index of the store.
+	indexOpts := store.DefaultIndexOptions()
+	if storeOpts.IndexOpts != nil {
+		baseIndexOpts := *storeOpts.IndexOpts
+		indexOpts = &baseIndexOpts
+	}
+	if c.config.IndexCacheSize > 0 {
+		indexOpts = indexOpts.WithCacheSize(c.config.IndexCacheSize)
+	}
+	if c.config.IndexFlushThreshold > 0 {
+		indexOpts = indexOpts.WithFlushThld(c.config.IndexFlushThreshold)
+	}
+	if c.config.IndexSyncThreshold > 0 {
+		indexOpts = indexOpts.WithSyncThld(c.config.IndexSyncThreshold)
+	}
+	storeOpts = storeOpts.WithIndexOptions(indexOpts)
+	// Assemble options for the sql engine.
+	sqlOpts := sql.DefaultOptions()
+	if c.config.SortBufferSize > 0 {
+		sqlOpts = sqlOpts.WithSortBufferSize(c.config.SortBufferSize)
+	}
+	if c.config.DistinctLimit > 0 {
+		sqlOpts = sqlOpts.WithDistinctLimit(c.config.DistinctLimit)
+	}
+	// The read only mode of the store cannot open the indexes
+	// of the sql engine. Instead all statements are executed
+	// in read only transactions.
+	txOpts := sql.DefaultTxOptions().
+		WithReadOnly(c.config.ReadOnly)
+	// Keep the store open until the connector is closed,
+	// so that it is not closed and reopened every time all
+	// connections of the pool have been closed.
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// The store is acquired again, if it has been closed using CloseEmbedded.
+	if !c.transient && (c.embeddedRef == nil || !c.embeddedRef.IsOpen()) {
+		ref, err := embedded.Acquire(c.config.Path, c.config.Name, storeOpts, sqlOpts)
+		if err != nil {
+			return nil, err
+		}
+		c.embeddedRef = ref
+	}
 	// Open an engine for it.
-	return embedded.Open(ctx, c.config.Path, c.config.Name)
+	return embedded.Open(ctx, embedded.Options{
+		Path:       c.config.Path,
+		Database:   c.config.Name,
+		Store:      storeOpts,
+		SQL:        sqlOpts,
+		Tx:         txOpts,
+		UUIDFormat: c.config.UUIDFormat,
+		Logger:     c.logger(),
+		Policy:     c.config.LogPolicy,
+	})
+}
+
+// logger returns the logger configured for the connector.
+// Without one the logger set by SetLogger is used.
+func (c *connector) logger() common.Logger {
+	if c.config.Logger != nil {
+		return adaptLogger(c.config.Logger)
+	}
+	return adaptLogger(getLogger())
 }
@@ -1,10 +1,23 @@
 package immusql
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"net/url"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/codenotary/immudb/pkg/client"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauu/immusql/common"
 )
 
 func TestConnectionResetClient(t *testing.T) {
@@ -29,3 +42,389 @@ func TestConnectionResetClient(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestConnectionResetEmbedded(t *testing.T) {
+	// Open the database.
+	db, err := openConnection(t)
+	if !assert.NoError(t, err, "An error occurred opening connection") {
+		t.Skip()
+	}
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+	require.NoError(t, err, "inserting data should not fail")
+	// Limit the maximum number of open and idle connections.
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(1)
+	// Create more concurrent request than the maximum number of open
+	// connections. This will force connections being closed and reset,
+	// while other connections are still using the shared store.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.Ping()
+			assert.NoError(t, err, "ping after opening the connection failed")
+			var name string
+			err = db.QueryRow("SELECT name FROM test").Scan(&name)
+			assert.NoError(t, err, "querying data should not fail")
+			assert.Equal(t, "Maria", name, "the queried name differs from the inserted one")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCloseEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	dsn := "immudbe://" + filepath.Join(dir, "defaultdb")
+	// Open two pools using the same store.
+	db1, err := sql.Open("immudb", dsn)
+	require.NoError(t, err, "opening DB connection should not fail")
+	defer db1.Close()
+	db2, err := sql.Open("immudb", dsn)
+	require.NoError(t, err, "opening DB connection should not fail")
+	defer db2.Close()
+	_, err = db1.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	_, err = db2.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+	require.NoError(t, err, "inserting data using a second pool should not fail")
+
+	// Closing one pool must not affect the other one.
+	require.NoError(t, db1.Close(), "closing the first pool should not fail")
+	_, err = db2.Exec("INSERT INTO test(name) VALUES(?)", "Marc")
+	require.NoError(t, err, "inserting data after closing the first pool should not fail")
+
+	// Explicitly closing the store makes it unusable for open connections.
+	conn, err := db2.Conn(context.Background())
+	require.NoError(t, err, "retrieving a connection should not fail")
+	require.NoError(t, CloseEmbedded(dir), "closing the embedded store should not fail")
+	_, err = conn.ExecContext(context.Background(), "INSERT INTO test(name) VALUES(?)", "Jose")
+	assert.Error(t, err, "inserting data after closing the store should fail")
+	require.NoError(t, conn.Close(), "releasing the connection should not fail")
+	require.NoError(t, db2.Close(), "closing the second pool should not fail")
+
+	// The store is opened again for new connections.
+	db3, err := sql.Open("immudb", dsn)
+	require.NoError(t, err, "opening DB connection should not fail")
+	defer db3.Close()
+	var count int
+	err = db3.QueryRow("SELECT COUNT(*) FROM test").Scan(&count)
+	require.NoError(t, err, "counting rows after reopening the store should not fail")
+	assert.Equal(t, 2, count, "rows stored before closing the store are missing")
+}
+
+func TestConnectionParamsClient(t *testing.T) {
+	port, err := startServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the immudb server should not fail")
+	// Open a connection using parameters tuning the client.
+	params := url.Values{
+		"dialTimeout":          []string{"5s"},
+		"heartBeatFrequency":   []string{"10s"},
+		"maxRecvMsgSize":       []string{"8388608"},
+		"stateDir":             []string{t.TempDir()},
+		"disableIdentityCheck": []string{"true"},
+	}
+	db, err := sql.Open("immudb", clientDSN(t, port, params))
+	require.NoError(t, err, "opening DB connection should not fail")
+	defer db.Close()
+	err = db.Ping()
+	assert.NoError(t, err, "ping after opening the connection failed")
+}
+
+func TestConnectionParamsEmbedded(t *testing.T) {
+	// Open an embedded engine using parameters tuning the store.
+	params := url.Values{
+		"async":               []string{"true"},
+		"syncFrequency":       []string{"10ms"},
+		"maxConcurrency":      []string{"10"},
+		"maxIOConcurrency":    []string{"2"},
+		"fileSize":            []string{"1048576"},
+		"compression":         []string{"gzip"},
+		"compressionLevel":    []string{"9"},
+		"txLogCacheSize":      []string{"500"},
+		"vLogCacheSize":       []string{"200"},
+		"indexCacheSize":      []string{"4096"},
+		"indexFlushThreshold": []string{"1000"},
+		"indexSyncThreshold":  []string{"2000"},
+		"sortBufferSize":      []string{"512"},
+		"distinctLimit":       []string{"100"},
+	}
+	dsn := url.URL{
+		Scheme:   "immudbe",
+		Path:     filepath.Join(t.TempDir(), "defaultdb"),
+		RawQuery: params.Encode(),
+	}
+	db, err := sql.Open("immudb", dsn.String())
+	require.NoError(t, err, "opening DB connection should not fail")
+	defer db.Close()
+
+	// Verify that data can be written and read.
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+	require.NoError(t, err, "inserting data should not fail")
+	var name string
+	err = db.QueryRow("SELECT name FROM test").Scan(&name)
+	require.NoError(t, err, "querying data should not fail")
+	assert.Equal(t, "Maria", name, "the queried name differs from the inserted one")
+}
+
+func TestConnectionReadOnlyEmbedded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaultdb")
+	// Create a table and insert data using a writable engine.
+	db, err := sql.Open("immudb", "immudbe://"+path)
+	require.NoError(t, err, "opening DB connection should not fail")
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+	require.NoError(t, err, "inserting data should not fail")
+	require.NoError(t, db.Close(), "closing the database should not fail")
+
+	// Reopen the engine in read only mode.
+	db, err = sql.Open("immudb", "immudbe://"+path+"?readOnly=true")
+	require.NoError(t, err, "opening DB connection should not fail")
+	defer db.Close()
+	var name string
+	err = db.QueryRow("SELECT name FROM test").Scan(&name)
+	require.NoError(t, err, "querying data in read only mode should not fail")
+	assert.Equal(t, "Maria", name, "the queried name differs from the inserted one")
+	_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Marc")
+	assert.Error(t, err, "inserting data in read only mode should fail")
+
+	// Transactions are also read only.
+	tx, err := db.Begin()
+	require.NoError(t, err, "beginning a transaction in read only mode should not fail")
+	defer tx.Rollback()
+	_, err = tx.Exec("INSERT INTO test(name) VALUES(?)", "Marc")
+	assert.Error(t, err, "inserting data in a transaction in read only mode should fail")
+}
+
+func TestNewConnectorClient(t *testing.T) {
+	port, err := startServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the immudb server should not fail")
+	// Create a connector from a configuration.
+	connector, err := NewConnector(Config{
+		User:          "immudb",
+		Pass:          "immudb",
+		Port:          port,
+		ClientOptions: client.DefaultOptions().WithDir(t.TempDir()),
+	})
+	require.NoError(t, err, "creating a connector should not fail")
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	err = db.Ping()
+	assert.NoError(t, err, "ping after opening the connection failed")
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	assert.NoError(t, err, "creating a table should not fail")
+}
+
+func TestNewConnectorEmbedded(t *testing.T) {
+	// Create a connector from a configuration.
+	connector, err := NewConnector(Config{
+		Embedded:     true,
+		Path:         t.TempDir(),
+		StoreOptions: store.DefaultOptions().WithSynced(false),
+	})
+	require.NoError(t, err, "creating a connector should not fail")
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+	require.NoError(t, err, "inserting data should not fail")
+	var name string
+	err = db.QueryRow("SELECT name FROM test").Scan(&name)
+	require.NoError(t, err, "querying data should not fail")
+	assert.Equal(t, "Maria", name, "the queried name differs from the inserted one")
+}
+
+func TestNewConnectorInvalid(t *testing.T) {
+	// An embedded engine requires a path.
+	_, err := NewConnector(Config{Embedded: true})
+	assert.Error(t, err, "creating an embedded connector without path should fail")
+	// A secured connection requires a tls configuration.
+	_, err = NewConnector(Config{TLS: true, TLSName: "unknown"})
+	assert.ErrorIs(t, err, common.ErrNoConfigRegistered, "using an unregistered tls configuration should fail")
+}
+
+// Verify that the driver and the connector satisfy the interfaces of database/sql.
+var (
+	_ driver.DriverContext = &ImmudbDriver{}
+	_ driver.Connector     = &connector{}
+	_ io.Closer            = &connector{}
+)
+
+func TestConnectorOpenDB(t *testing.T) {
+	port, err := startServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the immudb server should not fail")
+	dsns := map[string]string{
+		"embedded": "immudbe://" + filepath.Join(t.TempDir(), "defaultdb"),
+		"client":   clientDSN(t, port, nil),
+	}
+	for name, dsn := range dsns {
+		t.Run(name, func(t *testing.T) {
+			// Create a connector using the driver and open a database with it.
+			connector, err := (&ImmudbDriver{}).OpenConnector(dsn)
+			require.NoError(t, err, "creating a connector should not fail")
+			assert.IsType(t, &ImmudbDriver{}, connector.Driver(), "the connector returned an unexpected driver")
+			db := sql.OpenDB(connector)
+			defer db.Close()
+			err = db.Ping()
+			assert.NoError(t, err, "ping after opening the connection failed")
+			_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+			assert.NoError(t, err, "creating a table should not fail")
+		})
+	}
+	// An invalid dsn is already reported when opening the database.
+	_, err = sql.Open("immudb", "immudbx://localhost/defaultdb")
+	assert.Error(t, err, "opening a database with an invalid dsn should fail")
+}
+
+func TestConnectorCancel(t *testing.T) {
+	// Create a server, which accepts connections but never responds.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "creating a listener should not fail")
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+	connector, err := NewConnector(Config{
+		User:          "immudb",
+		Pass:          "immudb",
+		Port:          port,
+		ClientOptions: client.DefaultOptions().WithDir(t.TempDir()),
+	})
+	require.NoError(t, err, "creating a connector should not fail")
+
+	// Connecting has to be aborted once the deadline is exceeded.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = connector.Connect(ctx)
+	assert.Error(t, err, "connecting to an unresponsive server should fail")
+	assert.Less(t, time.Since(start), 5*time.Second, "connecting was not aborted after the deadline")
+
+	// Connecting with a cancelled context has to fail immediately.
+	embeddedConnector, err := NewConnector(Config{Embedded: true, Path: t.TempDir()})
+	require.NoError(t, err, "creating a connector should not fail")
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = connector.Connect(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "connecting with a cancelled context should fail")
+	_, err = embeddedConnector.Connect(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "connecting with a cancelled context should fail")
+}
+
+func TestConnectorDialContext(t *testing.T) {
+	port, err := startServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the immudb server should not fail")
+	// Count the connections established by the custom dial function.
+	var dialed atomic.Int32
+	dialer := net.Dialer{}
+	connector, err := NewConnector(Config{
+		User: "immudb",
+		Pass: "immudb",
+		Port: port,
+		DialContext: func(ctx context.Context, addr string) (net.Conn, error) {
+			dialed.Add(1)
+			return dialer.DialContext(ctx, "tcp", addr)
+		},
+		ClientOptions: client.DefaultOptions().WithDir(t.TempDir()),
+	})
+	require.NoError(t, err, "creating a connector should not fail")
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	err = db.Ping()
+	require.NoError(t, err, "ping after opening the connection failed")
+	assert.Greater(t, dialed.Load(), int32(0), "the custom dial function was not used")
+}
+
+func TestConnectorClose(t *testing.T) {
+	path := t.TempDir()
+	// Connections of a connector share the store, which is kept open
+	// until the connector is closed.
+	connector, err := NewConnector(Config{Embedded: true, Path: path})
+	require.NoError(t, err, "creating a connector should not fail")
+	conn, err := connector.Connect(context.Background())
+	require.NoError(t, err, "connecting should not fail")
+	require.NoError(t, conn.Close(), "closing the connection should not fail")
+
+	// Options of a second connector for the same path are ignored,
+	// as long as the store is opened by the first connector.
+	invalidConfig := Config{
+		Embedded:     true,
+		Path:         path,
+		StoreOptions: store.DefaultOptions().WithMaxConcurrency(0),
+	}
+	invalidConnector, err := NewConnector(invalidConfig)
+	require.NoError(t, err, "creating a connector should not fail")
+	conn, err = invalidConnector.Connect(context.Background())
+	require.NoError(t, err, "connecting to an opened store should not fail")
+	require.NoError(t, conn.Close(), "closing the connection should not fail")
+	require.NoError(t, invalidConnector.(io.Closer).Close(), "closing the connector should not fail")
+
+	// Closing the first connector closes the store,
+	// which cannot be reopened with invalid options.
+	require.NoError(t, connector.(io.Closer).Close(), "closing the connector should not fail")
+	require.NoError(t, connector.(io.Closer).Close(), "closing the connector twice should not fail")
+	invalidConnector, err = NewConnector(invalidConfig)
+	require.NoError(t, err, "creating a connector should not fail")
+	_, err = invalidConnector.Connect(context.Background())
+	assert.Error(t, err, "opening a store with invalid options should fail")
+}
+
+func TestConnectorReacquire(t *testing.T) {
+	path := t.TempDir()
+	connector, err := NewConnector(Config{Embedded: true, Path: path})
+	require.NoError(t, err, "creating a connector should not fail")
+	defer connector.(io.Closer).Close()
+	conn, err := connector.Connect(context.Background())
+	require.NoError(t, err, "connecting should not fail")
+	require.NoError(t, conn.Close(), "closing the connection should not fail")
+
+	// After the store has been closed explicitly, the connector keeps
+	// the reopened store open again.
+	require.NoError(t, CloseEmbedded(path), "closing the embedded store should not fail")
+	conn, err = connector.Connect(context.Background())
+	require.NoError(t, err, "connecting after closing the store should not fail")
+	require.NoError(t, conn.Close(), "closing the connection should not fail")
+
+	// The store is still open, so the invalid options are ignored.
+	invalidConnector, err := NewConnector(Config{
+		Embedded:     true,
+		Path:         path,
+		StoreOptions: store.DefaultOptions().WithMaxConcurrency(0),
+	})
+	require.NoError(t, err, "creating a connector should not fail")
+	defer invalidConnector.(io.Closer).Close()
+	conn, err = invalidConnector.Connect(context.Background())
+	require.NoError(t, err, "connecting to an opened store should not fail")
+	require.NoError(t, conn.Close(), "closing the connection should not fail")
+}
+
+func TestDriverOpenEmbedded(t *testing.T) {
+	path := t.TempDir()
+	// A connection opened without a connector does not keep the store open.
+	conn, err := (&ImmudbDriver{}).Open("immudbe://" + filepath.Join(path, "defaultdb"))
+	require.NoError(t, err, "opening a connection should not fail")
+	require.NoError(t, conn.Close(), "closing the connection should not fail")
+
+	// The store has been closed, so it cannot be reopened with invalid options.
+	invalidConnector, err := NewConnector(Config{
+		Embedded:     true,
+		Path:         path,
+		StoreOptions: store.DefaultOptions().WithMaxConcurrency(0),
+	})
+	require.NoError(t, err, "creating a connector should not fail")
+	_, err = invalidConnector.Connect(context.Background())
+	assert.Error(t, err, "opening a store with invalid options should fail")
+}
@@ -0,0 +1,162 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// insertRows inserts n rows into a new test table.
+func insertRows(t *testing.T, db *sql.DB, n int) {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	values := make([]string, n)
+	for i := range values {
+		values[i] = fmt.Sprintf("('name%d')", i)
+	}
+	_, err = db.Exec("INSERT INTO test(name) VALUES " + strings.Join(values, ", "))
+	require.NoError(t, err, "inserting data should not fail")
+}
+
+// queryDriver executes a query using the driver connection directly,
+// as database/sql checks the context itself before passing it on.
+func queryDriver(ctx context.Context, driverConn driver.Conn, query string) (driver.Rows, error) {
+	stmt, err := driverConn.(driver.ConnPrepareContext).PrepareContext(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	return stmt.(driver.StmtQueryContext).QueryContext(ctx, nil)
+}
+
+// execDriver executes a statement using the driver connection directly.
+func execDriver(ctx context.Context, driverConn driver.Conn, query string) error {
+	stmt, err := driverConn.(driver.ConnPrepareContext).PrepareContext(context.Background(), query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.(driver.StmtExecContext).ExecContext(ctx, nil)
+	return err
+}
+
+// readRows reads all remaining rows and returns the first error.
+func readRows(rows driver.Rows) error {
+	dest := make([]driver.Value, len(rows.Columns()))
+	for {
+		err := rows.Next(dest)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestContextDeadlineEmbedded(t *testing.T) {
+	db, err := openConnection(t)
+	require.NoError(t, err, "opening a connection should not fail")
+	defer db.Close()
+	insertRows(t, db, 10)
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err, "retrieving a connection should not fail")
+	defer conn.Close()
+
+	err = conn.Raw(func(c any) error {
+		driverConn := c.(driver.Conn)
+
+		// Without a deadline all rows are read.
+		rows, err := queryDriver(context.Background(), driverConn, "SELECT id FROM test")
+		require.NoError(t, err, "the query should not fail without a deadline")
+		assert.NoError(t, readRows(rows), "reading the rows should not fail without a deadline")
+		require.NoError(t, rows.Close(), "closing the rows should not fail")
+
+		// Reading the rows stops, once the context of the query is cancelled.
+		ctx, cancel := context.WithCancel(context.Background())
+		rows, err = queryDriver(ctx, driverConn, "SELECT id FROM test")
+		require.NoError(t, err, "the query should not fail")
+		dest := make([]driver.Value, 1)
+		require.NoError(t, rows.Next(dest), "reading the first row should not fail")
+		cancel()
+		assert.ErrorIs(t, readRows(rows), context.Canceled, "reading the rows should be aborted by cancelling the query")
+		require.NoError(t, rows.Close(), "closing the rows should not fail")
+
+		// A passed deadline of the query aborts it.
+		ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		rows, err = queryDriver(ctx, driverConn, "SELECT id FROM test")
+		if err == nil {
+			err = readRows(rows)
+			rows.Close()
+		}
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "the query should be aborted by the deadline")
+
+		// Transactions use the context with which they were started.
+		txCtx, cancelTx := context.WithCancel(context.Background())
+		tx, err := driverConn.(driver.ConnBeginTx).BeginTx(txCtx, driver.TxOptions{})
+		require.NoError(t, err, "beginning a transaction should not fail")
+		require.NoError(t, execDriver(context.Background(), driverConn, "INSERT INTO test(name) VALUES('tx')"),
+			"executing a statement in the transaction should not fail")
+		cancelTx()
+		rows, err = queryDriver(context.Background(), driverConn, "SELECT id FROM test")
+		if err == nil {
+			err = readRows(rows)
+			rows.Close()
+		}
+		assert.ErrorIs(t, err, context.Canceled, "the query in the transaction should be aborted by cancelling it")
+		assert.Error(t, tx.Commit(), "the transaction should not be committed after it has been cancelled")
+
+		// Statements are not executed with a cancelled context.
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		err = execDriver(ctx, driverConn, "INSERT INTO test(name) VALUES('late')")
+		assert.ErrorIs(t, err, context.Canceled, "the statement should not be executed with a cancelled context")
+		return nil
+	})
+	require.NoError(t, err, "accessing the driver connection should not fail")
+
+	// The connections remain usable.
+	var count int64
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM test").Scan(&count), "querying data should not fail")
+	assert.Equal(t, int64(10), count, "no further rows should have been inserted")
+}
+
+func TestContextDeadlineSlowQuery(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		insertRows(t, db, 100)
+		conn, err := db.Conn(context.Background())
+		require.NoError(t, err, "retrieving a connection should not fail")
+		defer conn.Close()
+
+		// The cross join of three tables yields a million rows, which takes
+		// several seconds. The deadline expires while they are retrieved.
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		read := 0
+		rows, err := conn.QueryContext(ctx, "SELECT a.id FROM test AS a INNER JOIN test AS b ON a.id > 0 INNER JOIN test AS c ON c.id > 0")
+		if err == nil {
+			for rows.Next() {
+				read++
+			}
+			err = rows.Err()
+			rows.Close()
+		}
+		assert.ErrorIs(t, err, context.DeadlineExceeded, "the query should be aborted by the deadline")
+		assert.Less(t, read, 100*100*100, "the query should have been interrupted before all rows were read")
+
+		// The connection remains usable after the query has been aborted.
+		var count int64
+		require.NoError(t, conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM test").Scan(&count),
+			"querying data using the connection should not fail")
+		assert.Equal(t, int64(100), count, "the connection should read all rows")
+	})
+}
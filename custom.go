@@ -1,7 +1,71 @@
 package immusql
 
+import (
+	"context"
+
+	"github.com/tauu/immusql/common"
+)
+
+// ErrVerificationFailed is returned if data retrieved from immudb
+// could not be verified and may have been tampered with.
+var ErrVerificationFailed = common.ErrVerificationFailed
+
+// ErrPrimaryKeyMismatch is returned if the number of values given
+// for a primary key does not match the columns of the primary key.
+var ErrPrimaryKeyMismatch = common.ErrPrimaryKeyMismatch
+
+// ErrReadOnlyTx is returned if a statement modifying data
+// is executed as part of a read only transaction.
+var ErrReadOnlyTx = common.ErrReadOnlyTx
+
+// ErrIsolationLevelNotSupported is returned when beginning a transaction with
+// LevelWriteCommitted or LevelLinearizable, which immudb cannot provide.
+// All other isolation levels are accepted.
+var ErrIsolationLevelNotSupported = common.ErrIsolationLevelNotSupported
+
+// ErrUnsupportedParam is returned if a parameter of a statement
+// has a type, which cannot be converted into an immudb value.
+var ErrUnsupportedParam = common.ErrUnsupportedParam
+
+// ErrParamOverflow is returned if an unsigned integer passed
+// as parameter exceeds the range of INTEGER values.
+var ErrParamOverflow = common.ErrParamOverflow
+
+// ErrInvalidIdentifier is returned if the name of a table or column
+// passed to a function of ImmuDBconn is not a valid identifier.
+var ErrInvalidIdentifier = common.ErrInvalidIdentifier
+
+// ErrInvalidSnapshot is returned if a query is executed using a context
+// created by WithTxSnapshot or WithTimestamp without a transaction or time.
+var ErrInvalidSnapshot = common.ErrInvalidSnapshot
+
+// ErrSnapshotPeriod is returned if a query executed at a snapshot
+// already selects a period of a table using e.g. SINCE or UNTIL.
+var ErrSnapshotPeriod = common.ErrSnapshotPeriod
+
+// Revision is a version of a row stored by a transaction.
+type Revision = common.Revision
+
 // ImmuDBconn exposes functions of an immudb connection
 // or an embedded engine, which cannot be called using the sql api.
 type ImmuDBconn interface {
 	ExistTable(name string) (bool, error)
+	// VerifiedGet retrieves the row of a table with the given primary key
+	// and cryptographically verifies it before returning its columns.
+	// The values of the primary key have to be in the order of the columns
+	// of the primary key. If no row exists, sql.ErrNoRows is returned.
+	// If the verification fails, the error wraps ErrVerificationFailed.
+	// An embedded engine trusts the state of its store when the first row
+	// is verified after opening it. This state is only kept in memory,
+	// so tampering before opening the store is not detected.
+	VerifiedGet(table string, pk ...any) (map[string]any, error)
+	// History retrieves every revision of the row of a table with the given
+	// primary key, including revisions deleting the row, ordered by their
+	// transactions. If the row never existed, no revisions are returned.
+	History(ctx context.Context, table string, pk ...any) ([]Revision, error)
+	// LastResult returns the result of the last statement executed or
+	// transaction committed using the connection. Statements executed as
+	// part of a transaction do not replace the result, their transaction
+	// is reported once it is committed.
+	LastResult() ImmuResult
 }
@@ -18,18 +18,21 @@ type ImmudbDriver struct{}
 // -- Driver interface --
 
 // Open a new connection to immudb.
+// The store of an embedded engine is closed together with the connection,
+// unless it is used by other connections.
 func (driver *ImmudbDriver) Open(dsn string) (driver.Conn, error) {
-	connector, err := driver.OpenConnector(dsn)
+	config, err := parseDSN(dsn)
 	if err != nil {
 		return nil, err
 	}
+	connector := &connector{config: config, transient: true}
 	return connector.Connect(context.Background())
 }
 
 // -- DriverContext interface --
 
 // OpenConnector creates a connector for opening connections to a immudb.
-func (driver *ImmudbDriver) OpenConnector(dsn string) (*connector, error) {
+func (driver *ImmudbDriver) OpenConnector(dsn string) (driver.Connector, error) {
 	config, err := parseDSN(dsn)
 	if err != nil {
 		return nil, err
@@ -2,40 +2,54 @@ package immusql
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/tauu/immusql/common"
 )
 
+// ParseDSN parses a dsn specifying an immudb database
+// into a configuration for connecting to it.
+func ParseDSN(dsn string) (Config, error) {
+	return parseDSN(dsn)
+}
+
 // parseDSN parses a dsn specifying a immudb database
 // into a configuration for connecting to it.
-func parseDSN(dsn string) (dsnConfig, error) {
-	conf := dsnConfig{}
+func parseDSN(dsn string) (Config, error) {
+	conf := Config{}
 	url, err := url.Parse(dsn)
 	if err != nil {
 		return conf, fmt.Errorf("parsing dsn %s failed: %v", dsn, err)
 	}
-	if url.Scheme != "immudb" && url.Scheme != "immudbe" {
-		return conf, fmt.Errorf("scheme of dsn is %s but has to be immudb or immudbe", url.Scheme)
+	if url.Scheme != "immudb" && url.Scheme != "immudbs" && url.Scheme != "immudbe" {
+		return conf, fmt.Errorf("scheme of dsn is %s but has to be immudb, immudbs or immudbe", url.Scheme)
 	}
 	// The immudbe scheme indicates that an embedded engine should be created.
 	if url.Scheme == "immudbe" {
 		conf.Embedded = true
 	}
+	// The immudbs scheme indicates that the connection is secured using mTLS.
+	if url.Scheme == "immudbs" {
+		conf.TLS = true
+	}
 	conf.User = url.User.Username()
 	conf.Pass, _ = url.User.Password()
 	// If no hostname is specified, use localhost as default.
 	host := url.Hostname()
 	if host == "" {
-		conf.Host = "localhost"
+		conf.Host = defaultHost
 	} else {
 		conf.Host = host
 	}
 	// If no port is specified, use the default port 3322.
 	port := url.Port()
 	if port == "" {
-		conf.Port = 3322
+		conf.Port = defaultPort
 	} else {
 		portInt, err := strconv.Atoi(port)
 		if err != nil {
@@ -63,7 +77,235 @@ func parseDSN(dsn string) (dsnConfig, error) {
 	}
 	// If no database is given, use the database named defaultdb.
 	if conf.Name == "" {
-		conf.Name = "defaultdb"
+		conf.Name = defaultDatabase
+	}
+	// Parse the query parameters.
+	if err := parseDSNParams(&conf, url.Query()); err != nil {
+		return conf, err
+	}
+	// Verify that the mTLS configuration for secured connections is registered.
+	if err := conf.checkTLS(); err != nil {
+		return conf, err
 	}
 	return conf, nil
 }
+
+// parseDSNParams parses the query parameters of a dsn
+// into the corresponding fields of the configuration.
+func parseDSNParams(conf *Config, params url.Values) error {
+	for key, values := range params {
+		// Every parameter may only be given once.
+		if len(values) != 1 {
+			return fmt.Errorf("parameter %s has to be specified exactly once", key)
+		}
+		// Client and embedded connections support different parameters.
+		var err error
+		if conf.Embedded {
+			err = parseEmbeddedParam(conf, key, values[0])
+		} else {
+			err = parseClientParam(conf, key, values[0])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseClientParam parses a query parameter of a dsn for a client connection.
+func parseClientParam(conf *Config, key string, value string) (err error) {
+	switch key {
+	case "tls":
+		if !conf.TLS {
+			return fmt.Errorf("the tls parameter requires the immudbs scheme")
+		}
+		conf.TLSName = value
+	case "dialTimeout":
+		conf.DialTimeout, err = parseDurationParam(key, value)
+	case "healthCheckRetries":
+		conf.HealthCheckRetries, err = parseIntParam(key, value)
+	case "heartBeatFrequency":
+		conf.HeartBeatFrequency, err = parseDurationParam(key, value)
+	case "maxRecvMsgSize":
+		conf.MaxRecvMsgSize, err = parseIntParam(key, value)
+	case "tokenFile":
+		conf.TokenFile = value
+	case "stateDir":
+		conf.StateDir = value
+	case "disableIdentityCheck":
+		conf.DisableIdentityCheck, err = parseBoolParam(key, value)
+	case "uuidFormat":
+		conf.UUIDFormat, err = parseUUIDFormatParam(key, value)
+	default:
+		return fmt.Errorf("unknown parameter %s in dsn for client connections", key)
+	}
+	return err
+}
+
+// parseEmbeddedParam parses a query parameter of a dsn for an embedded engine.
+func parseEmbeddedParam(conf *Config, key string, value string) (err error) {
+	switch key {
+	case "readOnly":
+		conf.ReadOnly, err = parseBoolParam(key, value)
+	case "async":
+		conf.Async, err = parseBoolParam(key, value)
+	case "syncFrequency":
+		conf.SyncFrequency, err = parseDurationParam(key, value)
+	case "maxConcurrency":
+		conf.MaxConcurrency, err = parseIntParam(key, value)
+	case "maxIOConcurrency":
+		conf.MaxIOConcurrency, err = parseIntParam(key, value)
+	case "fileSize":
+		conf.FileSize, err = parseIntParam(key, value)
+	case "compression":
+		if _, ok := compressionFormats[value]; !ok {
+			return fmt.Errorf("parameter %s has to be one of none, flate, gzip, lzw or zlib but is %s", key, value)
+		}
+		conf.CompressionFormat = value
+	case "compressionLevel":
+		conf.CompressionLevel, err = parseIntParam(key, value)
+		if err == nil && conf.CompressionLevel > 9 {
+			return fmt.Errorf("parameter %s has to be between 1 and 9 but is %s", key, value)
+		}
+	case "txLogCacheSize":
+		conf.TxLogCacheSize, err = parseIntParam(key, value)
+	case "vLogCacheSize":
+		conf.VLogCacheSize, err = parseIntParam(key, value)
+	case "indexCacheSize":
+		conf.IndexCacheSize, err = parseIntParam(key, value)
+	case "indexFlushThreshold":
+		conf.IndexFlushThreshold, err = parseIntParam(key, value)
+	case "indexSyncThreshold":
+		conf.IndexSyncThreshold, err = parseIntParam(key, value)
+	case "sortBufferSize":
+		conf.SortBufferSize, err = parseIntParam(key, value)
+	case "distinctLimit":
+		conf.DistinctLimit, err = parseIntParam(key, value)
+	case "uuidFormat":
+		conf.UUIDFormat, err = parseUUIDFormatParam(key, value)
+	default:
+		return fmt.Errorf("unknown parameter %s in dsn for embedded connections", key)
+	}
+	return err
+}
+
+// parseDurationParam parses the value of a parameter as a positive duration.
+func parseDurationParam(key string, value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parsing parameter %s value '%s' as duration failed: %v", key, value, err)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("parameter %s has to be a positive duration but is %s", key, value)
+	}
+	return duration, nil
+}
+
+// parseIntParam parses the value of a parameter as a positive integer.
+func parseIntParam(key string, value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("parsing parameter %s value '%s' as integer failed: %v", key, value, err)
+	}
+	if number <= 0 {
+		return 0, fmt.Errorf("parameter %s has to be a positive integer but is %s", key, value)
+	}
+	return number, nil
+}
+
+// parseBoolParam parses the value of a parameter as a boolean.
+func parseBoolParam(key string, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("parsing parameter %s value '%s' as boolean failed: %v", key, value, err)
+	}
+	return b, nil
+}
+
+// parseUUIDFormatParam parses the value of a parameter as the name of a uuid format.
+func parseUUIDFormatParam(key string, value string) (UUIDFormat, error) {
+	format, ok := common.ParseUUIDFormat(value)
+	if !ok {
+		return format, fmt.Errorf("parameter %s has to be one of string, bytes or native but is %s", key, value)
+	}
+	return format, nil
+}
+
+// FormatDSN formats the configuration as a dsn, which can be parsed by ParseDSN.
+// Fields that cannot be represented in a dsn, like a logger, are omitted.
+func (cfg Config) FormatDSN() string {
+	dsn := url.URL{}
+	params := url.Values{}
+	// setParam adds a parameter to the dsn, if its value is not the zero value.
+	setParam := func(key string, value any) {
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				params.Set(key, v)
+			}
+		case int:
+			if v != 0 {
+				params.Set(key, strconv.Itoa(v))
+			}
+		case bool:
+			if v {
+				params.Set(key, strconv.FormatBool(v))
+			}
+		case time.Duration:
+			if v != 0 {
+				params.Set(key, v.String())
+			}
+		case UUIDFormat:
+			if v != UUIDFormatString {
+				params.Set(key, v.String())
+			}
+		}
+	}
+	if cfg.Embedded {
+		dsn.Scheme = "immudbe"
+		// The last element of the path is the name of the database.
+		dsn.Path = filepath.ToSlash(filepath.Join(cfg.Path, cfg.Name))
+		setParam("readOnly", cfg.ReadOnly)
+		setParam("async", cfg.Async)
+		setParam("syncFrequency", cfg.SyncFrequency)
+		setParam("maxConcurrency", cfg.MaxConcurrency)
+		setParam("maxIOConcurrency", cfg.MaxIOConcurrency)
+		setParam("fileSize", cfg.FileSize)
+		setParam("compression", cfg.CompressionFormat)
+		setParam("compressionLevel", cfg.CompressionLevel)
+		setParam("txLogCacheSize", cfg.TxLogCacheSize)
+		setParam("vLogCacheSize", cfg.VLogCacheSize)
+		setParam("indexCacheSize", cfg.IndexCacheSize)
+		setParam("indexFlushThreshold", cfg.IndexFlushThreshold)
+		setParam("indexSyncThreshold", cfg.IndexSyncThreshold)
+		setParam("sortBufferSize", cfg.SortBufferSize)
+		setParam("distinctLimit", cfg.DistinctLimit)
+		setParam("uuidFormat", cfg.UUIDFormat)
+	} else {
+		dsn.Scheme = "immudb"
+		// A tls configuration secures the connection, but cannot be
+		// represented in the dsn itself.
+		if cfg.TLS || cfg.TLSConfig != nil {
+			dsn.Scheme = "immudbs"
+			setParam("tls", cfg.TLSName)
+		}
+		if cfg.User != "" {
+			dsn.User = url.UserPassword(cfg.User, cfg.Pass)
+		}
+		dsn.Host = cfg.Host
+		if cfg.Port != 0 {
+			dsn.Host = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+		}
+		dsn.Path = "/" + cfg.Name
+		setParam("dialTimeout", cfg.DialTimeout)
+		setParam("healthCheckRetries", cfg.HealthCheckRetries)
+		setParam("heartBeatFrequency", cfg.HeartBeatFrequency)
+		setParam("maxRecvMsgSize", cfg.MaxRecvMsgSize)
+		setParam("tokenFile", cfg.TokenFile)
+		setParam("stateDir", cfg.StateDir)
+		setParam("disableIdentityCheck", cfg.DisableIdentityCheck)
+		setParam("uuidFormat", cfg.UUIDFormat)
+	}
+	dsn.RawQuery = params.Encode()
+	return dsn.String()
+}
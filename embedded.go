@@ -0,0 +1,16 @@
+package immusql
+
+import "github.com/tauu/immusql/embedded"
+
+// CloseEmbedded closes the store of embedded engines at the given path,
+// even if connections are still using it. The path is the directory
+// containing the store, which is the path of an immudbe dsn without
+// the trailing database name.
+//
+// The store is closed automatically once the last connection using
+// it has been closed. Therefore this function only has to be called,
+// if connections using the store may still be open,
+// e.g. during the shutdown of an application.
+func CloseEmbedded(path string) error {
+	return embedded.Close(path)
+}
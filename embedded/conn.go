@@ -3,6 +3,7 @@ package embedded
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"strings"
 
 	"github.com/codenotary/immudb/embedded/sql"
@@ -13,35 +14,83 @@ import (
 // immudbEmbedded is a connection to a immudb instance.
 type immudbEmbedded struct {
 	engine *sql.Engine
-	store  *store.ImmuStore
+	// shared is the store used by the engine,
+	// which is shared with other connections.
+	shared *sharedStore
 	sqlTx  *sql.SQLTx
+	// txOpts are the options of transactions
+	// created for statements executed outside of a transaction.
+	txOpts *sql.TxOptions
+	logger common.Logger
+	// policy defines how queries are logged.
+	policy common.LogPolicy
+	// uuidFormat is the format in which UUID values are returned.
+	uuidFormat common.UUIDFormat
+	// data identifies the connection in log messages.
+	data map[string]any
+	// lastResult is the result of the last statement executed outside
+	// of a transaction or transaction committed using the connection.
+	lastResult common.Result
+	// txReadOnly is set while a read only transaction is active.
+	txReadOnly bool
+	// txCtx is the context with which the active transaction has been begun.
+	txCtx context.Context
+	// poisoned is set once an error occurred, after which
+	// the connection must not be used anymore.
+	poisoned bool
+}
+
+// Options configure a connection to an embedded immudb engine.
+type Options struct {
+	// Path is the directory in which the store is located.
+	Path string
+	// Database is the name of the database in the store.
+	Database string
+	// Store and SQL are the options of the store and the sql engine.
+	Store *store.Options
+	SQL   *sql.Options
+	// Tx are the options of transactions created
+	// for statements executed outside of a transaction.
+	Tx *sql.TxOptions
+	// UUIDFormat is the format in which UUID values are returned.
+	UUIDFormat common.UUIDFormat
+	Logger     common.Logger
+	// Policy defines how queries are logged.
+	Policy common.LogPolicy
 }
 
 // Connect establishes a new connection to an immudb instance.
-func Open(ctx context.Context, path string, dbName string) (driver.Conn, error) {
-	// Open a catalog and data store for the sql engine.
-	catalogStore, err := store.Open(path, store.DefaultOptions().WithMultiIndexing(true))
-	if err != nil {
-		return nil, err
+// All connections to databases stored at the same path share a single store.
+// The store is only opened by the first connection using the given options
+// and closed after the last connection using it has been closed.
+func Open(ctx context.Context, opts Options) (driver.Conn, error) {
+	data := map[string]any{
+		"path":     opts.Path,
+		"database": opts.Database,
 	}
-	// Create a sql engine.
-	sqlOpts := sql.DefaultOptions().WithPrefix([]byte(dbName))
-	engine, err := sql.NewEngine(catalogStore, sqlOpts)
+	// Retrieve the sql engine and the store for it.
+	shared, engine, err := acquireEngine(opts.Path, opts.Database, opts.Store, opts.SQL)
 	if err != nil {
+		common.Log(ctx, opts.Logger, common.LogLevelError, "opening connection failed", withErr(data, err))
 		return nil, err
 	}
-	return &immudbEmbedded{engine: engine, store: catalogStore}, nil
+	common.Log(ctx, opts.Logger, common.LogLevelInfo, "connection opened", data)
+	return &immudbEmbedded{
+		engine:     engine,
+		shared:     shared,
+		txOpts:     opts.Tx,
+		uuidFormat: opts.UUIDFormat,
+		logger:     opts.Logger,
+		policy:     opts.Policy,
+		data:       data,
+	}, nil
 }
 
 // -- Conn interface --
 
 // Prepare prepares a sql statement.
 func (conn *immudbEmbedded) Prepare(query string) (driver.Stmt, error) {
-	stmts, err := sql.ParseSQL(strings.NewReader(query))
-	if err != nil {
-		return nil, err
-	}
-	return &stmt{query: stmts, conn: conn}, nil
+	return conn.prepare(context.Background(), query)
 }
 
 // Begin start a new transaction.
@@ -52,7 +101,26 @@ func (conn *immudbEmbedded) Begin() (driver.Tx, error) {
 
 // Close closes the database connection.
 func (conn *immudbEmbedded) Close() error {
-	return conn.store.Close()
+	// Abort if the connection has already been closed.
+	if conn.shared == nil {
+		return nil
+	}
+	// Discard a transaction, which has not been finished.
+	if conn.sqlTx != nil {
+		conn.sqlTx.Cancel()
+		conn.sqlTx = nil
+		conn.txCtx = nil
+	}
+	// Release the store, so that it can be closed
+	// once no connection is using it anymore.
+	err := conn.shared.release()
+	conn.shared = nil
+	if err != nil {
+		conn.log(context.Background(), common.LogLevelError, "closing connection failed", withErr(conn.data, err))
+		return err
+	}
+	conn.log(context.Background(), common.LogLevelInfo, "connection closed", conn.data)
+	return nil
 }
 
 // -- ConnBeginTx interface --
@@ -62,12 +130,21 @@ func (conn *immudbEmbedded) BeginTx(ctx context.Context, opts driver.TxOptions)
 	if conn.sqlTx != nil {
 		return nil, common.ErrNestedTxNotSupported
 	}
-	stmt := &sql.BeginTransactionStmt{}
-	sqlTx, err := conn.execStmt(stmt)
+	// The transaction is created using the requested options
+	// and turned into an explicit one by a BEGIN statement.
+	sqlTx, err := conn.newTx(ctx, opts)
+	if err == nil {
+		stmts := []sql.SQLStmt{&sql.BeginTransactionStmt{}}
+		sqlTx, _, err = conn.engine.ExecPreparedStmts(ctx, sqlTx, stmts, nil)
+	}
 	if err != nil {
-		return nil, err
+		conn.log(ctx, common.LogLevelError, "beginning transaction failed", withErr(nil, err))
+		return nil, conn.handleError(err)
 	}
 	conn.sqlTx = sqlTx
+	conn.txCtx = ctx
+	conn.txReadOnly = opts.ReadOnly || conn.txOpts.ReadOnly
+	conn.log(ctx, common.LogLevelDebug, "transaction started", nil)
 	return &tx{conn: conn, ctx: ctx}, nil
 }
 
@@ -75,7 +152,7 @@ func (conn *immudbEmbedded) BeginTx(ctx context.Context, opts driver.TxOptions)
 
 // PrepareContext prepares a sql statement.
 func (conn *immudbEmbedded) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
-	return conn.Prepare(query)
+	return conn.prepare(ctx, query)
 }
 
 // -- ExecerContext interface --
@@ -83,14 +160,10 @@ func (conn *immudbEmbedded) PrepareContext(ctx context.Context, query string) (d
 // ExecContext executes a statement and returns the result.
 func (conn *immudbEmbedded) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	// Create a statement.
-	stmts, err := sql.ParseSQL(strings.NewReader(query))
+	stmt, err := conn.prepare(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	stmt := stmt{
-		query: stmts,
-		conn:  conn,
-	}
 	// Execute the query.
 	return stmt.ExecContext(ctx, args)
 }
@@ -101,14 +174,10 @@ func (conn *immudbEmbedded) ExecContext(ctx context.Context, query string, args
 // This method if required to satisfy the QueryerContext interface of sql/driver.
 func (conn *immudbEmbedded) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	// Create a statement.
-	stmts, err := sql.ParseSQL(strings.NewReader(query))
+	stmt, err := conn.prepare(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	stmt := stmt{
-		query: stmts,
-		conn:  conn,
-	}
 	// Run it and return the result.
 	return stmt.QueryContext(ctx, args)
 }
@@ -117,7 +186,11 @@ func (conn *immudbEmbedded) QueryContext(ctx context.Context, query string, args
 
 // Ping performs a health check to verify if the connection is still alive.
 func (conn *immudbEmbedded) Ping(ctx context.Context) error {
-	// Nothing to do, as there is no database connection.
+	// There is no database connection, but the store may have been closed.
+	if !conn.IsValid() {
+		conn.log(ctx, common.LogLevelWarn, "ping failed: store has been closed", nil)
+		return driver.ErrBadConn
+	}
 	return nil
 }
 
@@ -125,11 +198,36 @@ func (conn *immudbEmbedded) Ping(ctx context.Context) error {
 
 // ResetSession is called by database/sql before the connection is reused.
 func (conn *immudbEmbedded) ResetSession(ctx context.Context) error {
-	// Currently there is nothing to reset,
+	// Discard the connection, if the store has been closed,
+	// while the connection was idle in the pool.
+	if !conn.IsValid() {
+		conn.log(ctx, common.LogLevelWarn, "resetting session failed: store has been closed", nil)
+		return driver.ErrBadConn
+	}
+	// Apart from the last result there is nothing to reset,
 	// as there is no session.
+	conn.lastResult = nil
+	conn.log(ctx, common.LogLevelDebug, "session reset", nil)
 	return nil
 }
 
+// -- NamedValueChecker interface --
+
+// CheckNamedValue converts the parameters of a statement
+// into values supported by immudb.
+func (conn *immudbEmbedded) CheckNamedValue(nv *driver.NamedValue) error {
+	return common.CheckNamedValue(nv)
+}
+
+// -- Validator interface --
+
+// IsValid is called by database/sql before the connection is returned to the
+// pool. A connection is discarded, if its store has been closed or an error
+// occurred, after which it must not be used anymore.
+func (conn *immudbEmbedded) IsValid() bool {
+	return conn.shared != nil && conn.shared.isOpen() && !conn.poisoned
+}
+
 // -- ImmuDB custom interface --
 
 // ExistTable checks if a table with the given name exist in the connected database.
@@ -143,11 +241,151 @@ func (conn *immudbEmbedded) ExistTable(name string) (bool, error) {
 	return catalog.ExistTable(name), nil
 }
 
+// LastResult returns the result of the last statement executed outside
+// of a transaction or transaction committed using the connection.
+func (conn *immudbEmbedded) LastResult() common.Result {
+	return conn.lastResult
+}
+
 // -- util --
+
+// prepare parses the query into a statement.
+func (conn *immudbEmbedded) prepare(ctx context.Context, query string) (*stmt, error) {
+	stmts, err := sql.ParseSQL(strings.NewReader(query))
+	if err != nil {
+		if !common.QueryLogDisabled(ctx) {
+			conn.log(ctx, common.LogLevelError, "parsing query failed", withErr(map[string]any{"sql": query}, err))
+		}
+		return nil, common.NewError(common.CodeSyntaxError, err)
+	}
+	return &stmt{query: stmts, text: query, conn: conn}, nil
+}
+
+// fatalErrors contains the errors of the store,
+// after which a connection must not be used anymore.
+var fatalErrors = []error{
+	store.ErrCorruptedData,
+	store.ErrCorruptedTxData,
+	store.ErrCorruptedCLog,
+	store.ErrCorruptedIndex,
+	store.ErrCorruptedAHtree,
+}
+
+// handleError translates an error of the engine. If the error is fatal,
+// the connection is poisoned, so that database/sql discards it instead of
+// returning it to the pool.
+func (conn *immudbEmbedded) handleError(err error) error {
+	// Transactions also report being closed using the error of a closed
+	// store, therefore it is only fatal, if the store has been closed.
+	fatal := errors.Is(err, store.ErrAlreadyClosed) && conn.shared != nil && !conn.shared.isOpen()
+	for _, target := range fatalErrors {
+		fatal = fatal || errors.Is(err, target)
+	}
+	if fatal {
+		conn.poisoned = true
+	}
+	return common.TranslateError(err)
+}
+
+// log writes a message to the logger of the connection.
+func (conn *immudbEmbedded) log(ctx context.Context, level common.LogLevel, msg string, data map[string]any) {
+	common.Log(ctx, conn.logger, level, msg, data)
+}
+
+// withErr returns a copy of data with the error added to it.
+func withErr(data map[string]any, err error) map[string]any {
+	res := make(map[string]any, len(data)+1)
+	for k, v := range data {
+		res[k] = v
+	}
+	res["err"] = err
+	return res
+}
+
+// newTx creates a new transaction using the options of the connection
+// combined with the options requested for the transaction.
+func (conn *immudbEmbedded) newTx(ctx context.Context, txOpts driver.TxOptions) (*sql.SQLTx, error) {
+	unsafeMVCC, err := common.CheckIsolation(txOpts.Isolation)
+	if err != nil {
+		return nil, err
+	}
+	// The engine keeps and modifies the options of a transaction,
+	// e.g. when it is turned into an explicit transaction.
+	// Each transaction therefore receives its own copy.
+	opts := *conn.txOpts
+	opts.ReadOnly = opts.ReadOnly || txOpts.ReadOnly
+	opts.UnsafeMVCC = opts.UnsafeMVCC || unsafeMVCC
+	return conn.engine.NewTx(ctx, &opts)
+}
+
+// snapshotTx creates a read only transaction for reading the database
+// at the snapshot of the given transaction. Its snapshot includes the
+// transaction, while later ones are excluded by the period clause of the query.
+func (conn *immudbEmbedded) snapshotTx(ctx context.Context, txID uint64) (*sql.SQLTx, error) {
+	opts := *conn.txOpts
+	opts.ReadOnly = true
+	// A transaction, which has not been committed yet, cannot be waited for.
+	opts.SnapshotMustIncludeTxID = func(lastPrecommittedTxID uint64) uint64 {
+		return min(txID, lastPrecommittedTxID)
+	}
+	opts.SnapshotRenewalPeriod = 0
+	return conn.engine.NewTx(ctx, &opts)
+}
+
+// readOnly checks if statements are executed in a read only transaction.
+func (conn *immudbEmbedded) readOnly() bool {
+	if conn.sqlTx != nil {
+		return conn.txReadOnly
+	}
+	return conn.txOpts.ReadOnly
+}
+
+// stmtContext returns the context for executing a statement. In a transaction
+// it is also cancelled, once the context of the transaction is done. The
+// returned function has to be called after the statement has been executed.
+func (conn *immudbEmbedded) stmtContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	txCtx := conn.txCtx
+	if conn.sqlTx == nil || txCtx == nil {
+		return context.WithCancel(ctx)
+	}
+	// The deadline is applied directly, so that the statement
+	// also fails with context.DeadlineExceeded once it passes.
+	cancelDeadline := context.CancelFunc(func() {})
+	if deadline, ok := txCtx.Deadline(); ok {
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	// A transaction, which has already been cancelled, cancels
+	// the statement immediately instead of asynchronously.
+	if err := txCtx.Err(); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		cancel()
+	}
+	stop := context.AfterFunc(txCtx, func() {
+		if !errors.Is(txCtx.Err(), context.DeadlineExceeded) {
+			cancel()
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+		cancelDeadline()
+	}
+}
+
 // execStmt executes a single statement and returns the new Tx.
-func (conn *immudbEmbedded) execStmt(stmt sql.SQLStmt) (*sql.SQLTx, error) {
+func (conn *immudbEmbedded) execStmt(ctx context.Context, stmt sql.SQLStmt) (*sql.SQLTx, error) {
+	// Without an active transaction, a new one is created
+	// using the options configured for the connection.
+	sqlTx := conn.sqlTx
+	if sqlTx == nil {
+		var err error
+		sqlTx, err = conn.newTx(ctx, driver.TxOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
 	stmts := []sql.SQLStmt{stmt}
-	sqlTx, _, err := conn.engine.ExecPreparedStmts(context.Background(),
-		conn.sqlTx, stmts, nil)
+	sqlTx, _, err := conn.engine.ExecPreparedStmts(ctx,
+		sqlTx, stmts, nil)
 	return sqlTx, err
 }
@@ -0,0 +1,72 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/tauu/immusql/common"
+)
+
+// historyPageSize is the number of revisions read from the index at once.
+const historyPageSize = 100
+
+// History retrieves all revisions of the row of a table with the given primary key.
+// The revisions are ordered by the transactions, which stored them.
+func (conn *immudbEmbedded) History(ctx context.Context, table string, pk ...any) ([]common.Revision, error) {
+	t, encPK, err := conn.primaryKey(ctx, table, pk)
+	if err != nil {
+		return nil, err
+	}
+	snap, key, err := conn.primaryIndex(ctx, t, encPK)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Close()
+	// The history of the index contains deleted revisions as well.
+	var revisions []common.Revision
+	for {
+		valRefs, hCount, err := snap.History(key, uint64(len(revisions)), false, historyPageSize)
+		if errors.Is(err, store.ErrKeyNotFound) || errors.Is(err, store.ErrNoMoreEntries) {
+			return revisions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, valRef := range valRefs {
+			revision, err := conn.revision(t, valRef)
+			if err != nil {
+				return nil, err
+			}
+			revisions = append(revisions, revision)
+		}
+		if uint64(len(revisions)) >= hCount {
+			return revisions, nil
+		}
+	}
+}
+
+// revision decodes the revision of a row referenced by the primary index.
+func (conn *immudbEmbedded) revision(t *sql.Table, valRef store.ValueRef) (common.Revision, error) {
+	value, err := valRef.Resolve()
+	if err != nil {
+		return common.Revision{}, err
+	}
+	values, err := decodeRow(t, value)
+	if err != nil {
+		return common.Revision{}, err
+	}
+	hdr, err := conn.engine.GetStore().ReadTxHeader(valRef.Tx(), false, false)
+	if err != nil {
+		return common.Revision{}, err
+	}
+	md := valRef.KVMetadata()
+	return common.Revision{
+		TxID:      valRef.Tx(),
+		Timestamp: time.Unix(hdr.Ts, 0),
+		Deleted:   md != nil && md.Deleted(),
+		Values:    values,
+	}, nil
+}
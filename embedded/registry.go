@@ -0,0 +1,153 @@
+package embedded
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/embedded/store"
+)
+
+// sharedStore is a store, which is opened only once per path
+// and shared by all connections to databases stored at the path.
+type sharedStore struct {
+	path  string
+	store *store.ImmuStore
+	// engines contains the sql engine for each database in the store.
+	engines map[string]*sql.Engine
+	// refs is the number of connections using the store.
+	refs int
+	// verified is the latest state of the store,
+	// against which data has been verified.
+	verified verifiedState
+	// verifyLock guards access to verified.
+	verifyLock sync.Mutex
+}
+
+// stores contains all currently opened stores indexed by their path.
+var stores = make(map[string]*sharedStore)
+
+// storesLock guards access to stores and the fields of all shared stores.
+var storesLock sync.Mutex
+
+// acquireEngine retrieves the sql engine for the database with the given name
+// stored at path. If the store or the engine have not been opened yet,
+// they are created using the given options. Otherwise the options are ignored.
+// Every successful call has to be paired with a call of release.
+func acquireEngine(path string, name string, storeOpts *store.Options, sqlOpts *sql.Options) (*sharedStore, *sql.Engine, error) {
+	// Use the absolute path to identify the store.
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	storesLock.Lock()
+	defer storesLock.Unlock()
+	// Open the store, if it has not been opened yet.
+	shared, ok := stores[path]
+	if !ok {
+		catalogStore, err := store.Open(path, storeOpts)
+		if err != nil {
+			return nil, nil, err
+		}
+		shared = &sharedStore{
+			path:    path,
+			store:   catalogStore,
+			engines: make(map[string]*sql.Engine),
+		}
+		stores[path] = shared
+	}
+	// Create a sql engine for the database, if none exists yet.
+	engine, ok := shared.engines[name]
+	if !ok {
+		engine, err = sql.NewEngine(shared.store, sqlOpts.WithPrefix([]byte(name)))
+		if err != nil {
+			// Do not keep a store open, which is not used by any connection.
+			if shared.refs == 0 {
+				shared.close()
+			}
+			return nil, nil, err
+		}
+		shared.engines[name] = engine
+	}
+	shared.refs++
+	return shared, engine, nil
+}
+
+// release informs the shared store that a connection no longer uses it.
+// The store is closed as soon as no connection is using it anymore.
+func (shared *sharedStore) release() error {
+	storesLock.Lock()
+	defer storesLock.Unlock()
+	// Nothing has to be done, if the store has already been closed.
+	if stores[shared.path] != shared {
+		return nil
+	}
+	shared.refs--
+	if shared.refs > 0 {
+		return nil
+	}
+	return shared.close()
+}
+
+// isOpen checks if the store has not been closed yet.
+func (shared *sharedStore) isOpen() bool {
+	storesLock.Lock()
+	defer storesLock.Unlock()
+	return stores[shared.path] == shared
+}
+
+// close closes the store and removes it from the opened stores.
+// The caller has to hold storesLock.
+func (shared *sharedStore) close() error {
+	delete(stores, shared.path)
+	return shared.store.Close()
+}
+
+// Reference keeps a shared store open without being a connection.
+type Reference struct {
+	shared *sharedStore
+}
+
+// Acquire opens the store at path and the sql engine for the database with
+// the given name in the same way as Open, but without creating a connection.
+// The store is kept open at least until the returned reference is closed.
+func Acquire(path string, dbName string, storeOpts *store.Options, sqlOpts *sql.Options) (*Reference, error) {
+	shared, _, err := acquireEngine(path, dbName, storeOpts, sqlOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &Reference{shared: shared}, nil
+}
+
+// IsOpen checks if the referenced store is still open. It is closed
+// regardless of the reference, if Close is called for its path.
+func (r *Reference) IsOpen() bool {
+	return r.shared != nil && r.shared.isOpen()
+}
+
+// Close releases the store referenced by r.
+func (r *Reference) Close() error {
+	// Abort if the reference has already been released.
+	if r.shared == nil {
+		return nil
+	}
+	err := r.shared.release()
+	r.shared = nil
+	return err
+}
+
+// Close closes the store at the given path,
+// even if connections are still using it.
+func Close(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	storesLock.Lock()
+	defer storesLock.Unlock()
+	shared, ok := stores[path]
+	if !ok {
+		return nil
+	}
+	return shared.close()
+}
@@ -9,90 +9,33 @@ import (
 	"time"
 
 	"github.com/codenotary/immudb/embedded/sql"
-	"github.com/google/uuid"
 	"github.com/tauu/immusql/common"
 )
 
 // result contains the data reported by immudb after executing a statement.
-// The embedded engine does not seem to report any useful information,
-// after an execution. Therefore this does not store any data at the moment.
 type result struct {
-	previousLastInsertedPKs map[string]int64
-	tx                      *sql.SQLTx
-	committedTx             []*sql.SQLTx
+	// TxResult contains the metadata of the committed transactions.
+	common.TxResult
+	// insertedPKs contains the last primary key
+	// inserted by the statement into each table.
+	insertedPKs map[string]int64
+	tx          *sql.SQLTx
+	committedTx []*sql.SQLTx
 }
 
 // -- Result interface --
 
-// LastInsertId returns the id of the last row insterted by a statement.
+// LastInsertId returns the id of the last row inserted by a statement.
+// If the statement inserted rows into more than one table with an auto
+// increment primary key, the id is ambiguous and -1 is returned.
+// LastInsertedPKs provides the ids for each table in this case.
 func (r result) LastInsertId() (int64, error) {
-	// If no summary has been set, there is no id available.
-	if r.tx == nil {
-		return -1, nil
-
-	}
-
-	// Load all last inserted PKs.
-	// This yields a map, with (table_name => last_inserted_id) pairs.
-	// As maps in golang are not ordered in any way, there is no way to determine,
-	// which value was inserted last.
-	// If the query corresponding to this result is part of a transaction,
-	// lastPKs can contain multiple entries. More specifically, the map will
-	// contain one value for each table with an autoincrement column, into which
-	// a row was inserted during the transaction.
-	lastPKs := r.tx.LastInsertedPKs()
-
-	// To determine the one id that was inserted last, we have to consider 2 cases.
-
-	// Case 1: The result does not correspond to a query being part of a transaction.
-	// In this there is no stored previous value for lastInsertedPKs.
-	// The map should contain one value, if it inserted a value
-	// into a table with an autoincrement column.
-	if r.previousLastInsertedPKs == nil {
-		if len(lastPKs) == 1 {
-			// The single value in lastPKs is the last primary key.
-			for _, id := range lastPKs {
-				return id, nil
-			}
+	if len(r.insertedPKs) == 1 {
+		for _, id := range r.insertedPKs {
+			return id, nil
 		}
-		// There is more than one value in LastInsertedPKs().
-		// As maps are not ordered in golang, there is no way,
-		// to determine which one was inserted last.
-		return -1, nil
-	}
-
-	// Case 2: The result corresponds to a query that is part of a transaction.
-	// Before the query was executed, the value os LastInsertedPKs() was copied
-	// into r.previousLastPKs. This value is compared with the current value.
-	lastPK := int64(-1)
-	foundPK := 0
-	for table, id := range lastPKs {
-		// Test if a last inserted PK value existed for this table previously.
-		previousId, ok := r.previousLastInsertedPKs[table]
-		// If no primary key value has been inserted into the table
-		// by the query corresponding to this result.
-		if !ok {
-			lastPK = id
-			foundPK++
-		}
-		// If the current last inserted PK value differs from the
-		// previous value for the table, a new primary key has been
-		// inserted into the table.
-		if ok && previousId != id {
-			lastPK = id
-			foundPK++
-		}
-	}
-	//fmt.Printf("lastInsertedID: %v, #foundPL: %v", lastPK, foundPK)
-	// Only if the comparison above found exactly one possible value
-	// for the last inserted PK, we do know its value.
-	if foundPK == 1 {
-		return lastPK, nil
 	}
-
-	// The last inserted PK could not be identified.
 	return -1, nil
-
 }
 
 // RowsAffected returns the number of rows affected by executing a statement.
@@ -113,9 +56,74 @@ func (r result) RowsAffected() (int64, error) {
 	return count, nil
 }
 
+// LastInsertedPKs returns the last value assigned by the
+// statement to the auto increment primary key of each table.
+func (r result) LastInsertedPKs() map[string]int64 {
+	pks := make(map[string]int64, len(r.insertedPKs))
+	for table, id := range r.insertedPKs {
+		pks[table] = id
+	}
+	return pks
+}
+
+// insertedPKs determines the last primary key inserted into each table by the
+// transactions committed by a statement and by the transaction still active
+// after it. The transaction, which was active before the statement has been
+// executed, may already contain inserted keys. They are given as previous
+// and only reported, if the statement changed them.
+func insertedPKs(active *sql.SQLTx, previous map[string]int64, committedTx []*sql.SQLTx, tx *sql.SQLTx) map[string]int64 {
+	pks := map[string]int64{}
+	for _, tx := range append(committedTx[:len(committedTx):len(committedTx)], tx) {
+		if tx == nil {
+			continue
+		}
+		for table, id := range tx.LastInsertedPKs() {
+			if previousID, ok := previous[table]; tx == active && ok && previousID == id {
+				continue
+			}
+			pks[table] = id
+		}
+	}
+	return pks
+}
+
+// txResult collects the metadata of the committed transactions.
+func txResult(txs []*sql.SQLTx) common.TxResult {
+	res := make(common.TxResult, 0, len(txs))
+	for _, tx := range txs {
+		// Transactions without any changes are not stored and have no header.
+		hdr := tx.TxHeader()
+		if hdr == nil {
+			continue
+		}
+		res = append(res, common.TxInfo{
+			ID:              hdr.ID,
+			Timestamp:       time.Unix(hdr.Ts, 0),
+			Hash:            hdr.Alh(),
+			LastInsertedPKs: tx.LastInsertedPKs(),
+		})
+	}
+	return res
+}
+
 // rows contains the rows retrieved by immudb after executing a query.
 type rows struct {
 	data sql.RowReader
+	// ctx is the context of the query, which is
+	// used for reading the rows of the result.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// query is the text of the query, which is used
+	// for resolving the tables of the columns.
+	query string
+	// engine and tx are used for looking up the definition
+	// of the columns in the catalog.
+	engine *sql.Engine
+	tx     *sql.SQLTx
+	// infos caches the definition of each column, once it has been looked up.
+	infos []*common.ColumnInfo
+	// uuidFormat is the format in which UUID values are returned.
+	uuidFormat common.UUIDFormat
 }
 
 // -- Rows interface --
@@ -123,7 +131,7 @@ type rows struct {
 // Columns returns the name of the columns of the rows.
 func (r *rows) Columns() []string {
 	// Retrieve the columns in the query result.
-	immudbCols, err := r.data.Columns(context.Background())
+	immudbCols, err := r.data.Columns(r.ctx)
 	// If an error occurred, it cannot be reported using the sql/driver interface.
 	// Instead we return an empty array.
 	if err != nil {
@@ -139,23 +147,23 @@ func (r *rows) Columns() []string {
 
 // Close closes the query result iterator.
 func (r *rows) Close() error {
-	// Currently there is nothing to close,
-	// as immudb will return all data at once.
+	// Release the context of the query after closing the reader.
+	defer r.cancel()
 	return r.data.Close()
 }
 
 // Next returns the next row of the query result.
 func (r *rows) Next(dest []driver.Value) error {
 	// Get the rows.
-	row, err := r.data.Read(context.Background())
+	row, err := r.data.Read(r.ctx)
 	if errors.Is(err, sql.ErrNoMoreRows) {
 		return io.EOF
 	}
 	if err != nil {
-		return err
+		return common.TranslateError(err)
 	}
 	// Retrieve the columns in the query result.
-	immudbCols, err := r.data.Columns(context.Background())
+	immudbCols, err := r.data.Columns(r.ctx)
 	if err != nil {
 		return err
 	}
@@ -167,6 +175,12 @@ func (r *rows) Next(dest []driver.Value) error {
 			break
 		}
 		value := values[col.Selector()]
+		// NULL values are always returned as nil. Otherwise the value of the
+		// previous row would be kept, as database/sql reuses dest.
+		if value == nil || value.IsNull() {
+			dest[i] = nil
+			continue
+		}
 		switch value.Type() {
 		case sql.IntegerType:
 			dest[i] = value.RawValue()
@@ -195,12 +209,14 @@ func (r *rows) Next(dest []driver.Value) error {
 		case sql.Float64Type:
 			dest[i] = value.RawValue()
 		case sql.UUIDType:
-			// Convert the uuid to a string, as the sql package does not support
-			// uuid values directly.
-			sqlUUID, ok := value.RawValue().(uuid.UUID)
-			if ok {
-				dest[i] = sqlUUID.String()
+			dest[i], err = common.UUIDValue(value.RawValue(), r.uuidFormat)
+			if err != nil {
+				return err
 			}
+		case sql.JSONType:
+			// JSON values are returned as their JSON text,
+			// which can be scanned into json.RawMessage.
+			dest[i] = []byte(value.String())
 		case sql.AnyType:
 			dest[i] = value.RawValue()
 		}
@@ -213,7 +229,7 @@ func (r *rows) Next(dest []driver.Value) error {
 // ColumnTypeDatabaseTypeName returns the type of the index-th column in the result.
 func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
 	// Retrieve the columns in the query result.
-	immudbCols, err := r.data.Columns(context.Background())
+	immudbCols, err := r.data.Columns(r.ctx)
 	if err != nil {
 		return ""
 	}
@@ -227,12 +243,21 @@ func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
 // -- RowsColumnTypeNullable interface --
 
 // ColumnTypeNullable returns if the index-th column in the result is nullable.
+// It is only known for columns, which are read directly from a table.
 func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
-	// Immudb does support nullable columns,
-	// but as of now, there does not seem to be a way,
-	// to determine if a column can be null from the reader.
-	// Therefore the result is always false.
-	return false, true
+	info := r.columnInfo(index)
+	if info == nil {
+		return false, false
+	}
+	return info.Nullable, true
+}
+
+// -- RowsColumnTypeLength interface --
+
+// ColumnTypeLength returns the maximum length of the index-th column in the
+// result, if it has a variable length type.
+func (r *rows) ColumnTypeLength(index int) (length int64, ok bool) {
+	return common.ColumnTypeLength(r.ColumnTypeDatabaseTypeName(index), r.columnInfo(index))
 }
 
 // -- RowsColumnTypePrecisionScale interface --
@@ -248,7 +273,7 @@ func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok b
 // ColumnTypeScanType returns the type of a go value into which the value of the index-th column can be scanned.
 func (r *rows) ColumnTypeScanType(index int) reflect.Type {
 	// Retrieve the columns in the query result.
-	immudbCols, err := r.data.Columns(context.Background())
+	immudbCols, err := r.data.Columns(r.ctx)
 	if err != nil {
 		return nil
 	}
@@ -256,5 +281,53 @@ func (r *rows) ColumnTypeScanType(index int) reflect.Type {
 		return nil
 	}
 	typeName := immudbCols[index].Type
-	return common.ColumnTypeScanType(typeName)
+	return common.ColumnTypeScanType(typeName, r.uuidFormat)
+}
+
+// -- helper --
+
+// columnInfo returns the definition of the index-th column in the catalog.
+// The definitions of all columns are looked up once and cached afterwards.
+func (r *rows) columnInfo(index int) *common.ColumnInfo {
+	if r.infos == nil {
+		immudbCols, err := r.data.Columns(r.ctx)
+		if err != nil {
+			return nil
+		}
+		selectors := make([]string, len(immudbCols))
+		for i, col := range immudbCols {
+			selectors[i] = col.Selector()
+		}
+		var catalog *sql.Catalog
+		r.infos = common.ColumnInfos(r.query, selectors, func(name string) (map[string]common.ColumnInfo, error) {
+			// The catalog is only retrieved, if a table has to be described.
+			if catalog == nil {
+				catalog, err = r.engine.Catalog(r.ctx, r.tx)
+				if err != nil {
+					return nil, err
+				}
+			}
+			table, err := catalog.GetTableByName(name)
+			if err != nil {
+				return nil, err
+			}
+			cols := make(map[string]common.ColumnInfo, len(table.Cols()))
+			for _, col := range table.Cols() {
+				// Columns of the primary key can never be NULL.
+				info := common.ColumnInfo{
+					Nullable: col.IsNullable() && !table.PrimaryIndex().IncludesCol(col.ID()),
+				}
+				// The length of other types is determined by the type itself.
+				if col.Type() == sql.VarcharType || col.Type() == sql.BLOBType {
+					info.MaxLen = col.MaxLen()
+				}
+				cols[col.Name()] = info
+			}
+			return cols, nil
+		})
+	}
+	if index >= len(r.infos) {
+		return nil
+	}
+	return r.infos[index]
 }
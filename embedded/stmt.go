@@ -3,6 +3,8 @@ package embedded
 import (
 	"context"
 	"database/sql/driver"
+	"strings"
+	"time"
 
 	"github.com/codenotary/immudb/embedded/sql"
 	"github.com/tauu/immusql/common"
@@ -11,7 +13,9 @@ import (
 // Stmt is a prepared SQL statement.
 type stmt struct {
 	query []sql.SQLStmt
-	conn  *immudbEmbedded
+	// text is the sql query the statement has been parsed from.
+	text string
+	conn *immudbEmbedded
 }
 
 // -- Stmt interface --
@@ -46,11 +50,23 @@ func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
 
 // ExecContext executes the statement and returns the result.
 func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	// Statements modifying data are rejected in read only transactions,
+	// before they are executed. This keeps the transaction usable.
+	if s.conn.readOnly() && !common.ReadOnly(s.query) {
+		common.LogQuery(ctx, s.conn.logger, s.conn.policy, "statement executed", s.text, args, start, common.ErrReadOnlyTx)
+		return nil, common.ErrReadOnlyTx
+	}
+
+	ctx, cancel := s.conn.stmtContext(ctx)
+	defer cancel()
 
 	// If the statement is part of a transaction
 	// the previous LastInsertedPKs are stored
 	// to determine later on, which PKs have been
 	// inserted by this statement.
+	active := s.conn.sqlTx
 	var previousLastInsertedPKs map[string]int64
 	if s.conn.sqlTx != nil {
 		lastPKs := s.conn.sqlTx.LastInsertedPKs()
@@ -61,33 +77,99 @@ func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (drive
 		}
 	}
 
+	// Outside of a transaction the statement is executed in a new
+	// transaction using the options configured for the connection.
+	sqlTx := s.conn.sqlTx
+	if sqlTx == nil {
+		var err error
+		sqlTx, err = s.conn.newTx(ctx, driver.TxOptions{})
+		if err != nil {
+			common.LogQuery(ctx, s.conn.logger, s.conn.policy, "statement executed", s.text, args, start, err)
+			return nil, s.conn.handleError(err)
+		}
+	}
+
 	// Convert arguments to the expected format and execute the query.
 	params := common.NamedValueToMapString(args)
-	tx, committedTx, err := s.conn.engine.ExecPreparedStmts(context.Background(), s.conn.sqlTx, s.query, params)
+	tx, committedTx, err := s.conn.engine.ExecPreparedStmts(ctx, sqlTx, s.query, params)
+	common.LogQuery(ctx, s.conn.logger, s.conn.policy, "statement executed", s.text, args, start, err)
 	if err != nil {
-		return nil, err
+		// Discard the newly created transaction, if it is still active.
+		if s.conn.sqlTx == nil && !sqlTx.Closed() {
+			sqlTx.Cancel()
+		}
+		return nil, s.conn.handleError(err)
 	}
 
-	return result{previousLastInsertedPKs: previousLastInsertedPKs, tx: tx, committedTx: committedTx}, nil
+	res := result{
+		TxResult:    txResult(committedTx),
+		insertedPKs: insertedPKs(active, previousLastInsertedPKs, committedTx, tx),
+		tx:          tx,
+		committedTx: committedTx,
+	}
+	// Statements executed as part of a transaction do not commit
+	// a transaction, which is reported when committing it instead.
+	if active == nil {
+		s.conn.lastResult = res
+	}
+	return res, nil
 }
 
 // -- StmtQueryContext interface --
 
 // QueryContext executes the statement and returns the retrieved rows.
 func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
 	// Convert arguments to the expected format and execute the query.
 	params := common.NamedValueToMapString(args)
-	if len(s.query) > 1 {
+	// Read the snapshot requested by the context,
+	// which requires parsing the rewritten query.
+	stmts, text := s.query, s.text
+	snapshot, hasSnapshot := common.SnapshotFrom(ctx)
+	if hasSnapshot {
+		var err error
+		text, err = common.ApplySnapshot(ctx, s.text)
+		if err != nil {
+			return nil, err
+		}
+		stmts, err = sql.ParseSQL(strings.NewReader(text))
+		if err != nil {
+			return nil, common.NewError(common.CodeSyntaxError, err)
+		}
+	}
+	if len(stmts) > 1 {
 		return nil, ErrQueriedMultipleStatements
 	}
-	stmt := s.query[0]
+	stmt := stmts[0]
 	switch q := stmt.(type) {
 	case *sql.SelectStmt:
-		res, err := s.conn.engine.QueryPreparedStmt(context.Background(), s.conn.sqlTx, q, params)
+		// The context is used for reading the rows
+		// and released once they have been closed.
+		ctx, cancel := s.conn.stmtContext(ctx)
+		sqlTx := s.conn.sqlTx
+		if sqlTx == nil && snapshot.TxID > 0 {
+			// The snapshot of the transaction is read in a transaction,
+			// which waits until the transaction has been indexed.
+			snapshotTx, err := s.conn.snapshotTx(ctx, snapshot.TxID)
+			if err != nil {
+				cancel()
+				return nil, s.conn.handleError(err)
+			}
+			sqlTx = snapshotTx
+			// The transaction is discarded, once the rows have been closed.
+			release := cancel
+			cancel = func() {
+				snapshotTx.Cancel()
+				release()
+			}
+		}
+		res, err := s.conn.engine.QueryPreparedStmt(ctx, sqlTx, q, params)
+		common.LogQuery(ctx, s.conn.logger, s.conn.policy, "query executed", text, args, start, err)
 		if err != nil {
-			return nil, err
+			cancel()
+			return nil, s.conn.handleError(err)
 		}
-		return &rows{data: res}, nil
+		return &rows{data: res, ctx: ctx, cancel: cancel, query: s.text, engine: s.conn.engine, tx: sqlTx, uuidFormat: s.conn.uuidFormat}, nil
 	default:
 		return nil, ErrQueriedNonSelectStatement
 	}
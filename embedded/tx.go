@@ -21,14 +21,28 @@ func (t *tx) Commit() error {
 	if t.conn.sqlTx == nil {
 		return common.ErrTxAlreadyFinished
 	}
-	err := t.conn.sqlTx.Commit(context.Background())
+	// Immudb refuses to commit read only transactions. As they cannot contain
+	// any changes, they are completed by cancelling them instead.
+	if t.conn.txReadOnly {
+		return t.release()
+	}
+	err := t.conn.sqlTx.Commit(t.ctx)
 	// Committing the transaction by executing the CommitStmt does not raise an
 	// error/ if there is not pending transaction or if an error occurred during
 	// the transaction. The client package causes an error in this situation,
 	// therefore the above method is used instead.
 	// Commit the transaction.
-	//_, err := t.conn.execStmt(&sql.CommitStmt{})
-	return t.finish(err)
+	//_, err := t.conn.execStmt(t.ctx, &sql.CommitStmt{})
+	if err != nil {
+		t.conn.log(t.ctx, common.LogLevelError, "committing transaction failed", withErr(nil, err))
+		// A failed commit already closes the transaction. The error of
+		// cancelling it would therefore hide the cause, e.g. a conflict.
+		t.finish(err)
+		return t.conn.handleError(err)
+	}
+	t.conn.lastResult = txResult([]*sql.SQLTx{t.conn.sqlTx})
+	t.conn.log(t.ctx, common.LogLevelDebug, "transaction committed", nil)
+	return t.finish(nil)
 }
 
 // Rollback rolls back the transaction.
@@ -38,7 +52,12 @@ func (t *tx) Rollback() error {
 		return common.ErrTxAlreadyFinished
 	}
 	// Rollback the transaction.
-	_, err := t.conn.execStmt(&sql.RollbackStmt{})
+	_, err := t.conn.execStmt(t.ctx, &sql.RollbackStmt{})
+	if err != nil {
+		t.conn.log(t.ctx, common.LogLevelError, "rolling back transaction failed", withErr(nil, err))
+	} else {
+		t.conn.log(t.ctx, common.LogLevelDebug, "transaction rolled back", nil)
+	}
 	// In any case mark the transaction as finished.
 	// Otherwise no further operations can be performed.
 	return t.finish(err)
@@ -46,6 +65,19 @@ func (t *tx) Rollback() error {
 
 // -- helper --
 
+// release completes a read only transaction by cancelling it.
+func (t *tx) release() error {
+	err := t.conn.sqlTx.Cancel()
+	if err != nil {
+		t.conn.log(t.ctx, common.LogLevelError, "rolling back read only transaction failed", withErr(nil, err))
+	} else {
+		t.conn.lastResult = common.TxResult{}
+		t.conn.log(t.ctx, common.LogLevelDebug, "read only transaction rolled back", nil)
+	}
+	t.finish(nil)
+	return err
+}
+
 // finish completes the transaction, and informs the connection
 // to no longer execute queries in the context of the transaction.
 func (t *tx) finish(err error) error {
@@ -56,5 +88,7 @@ func (t *tx) finish(err error) error {
 	}
 	// If the transaction was completed, remove it from the connection.
 	t.conn.sqlTx = nil
+	t.conn.txCtx = nil
+	t.conn.txReadOnly = false
 	return err
 }
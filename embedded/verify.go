@@ -0,0 +1,213 @@
+package embedded
+
+import (
+	"context"
+	"crypto/sha256"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/codenotary/immudb/embedded/sql"
+	"github.com/codenotary/immudb/embedded/store"
+	"github.com/tauu/immusql/common"
+)
+
+// verifiedState is a state of a store, which has been verified.
+type verifiedState struct {
+	txID uint64
+	alh  [sha256.Size]byte
+}
+
+// VerifiedGet retrieves the row of a table with the given primary key.
+// The row is verified using an inclusion proof for its transaction
+// and a dual proof between the transaction and the last verified state of the store.
+// The first verification after opening the store trusts its committed state.
+func (conn *immudbEmbedded) VerifiedGet(table string, pk ...any) (map[string]any, error) {
+	ctx := context.Background()
+	t, encPK, err := conn.primaryKey(ctx, table, pk)
+	if err != nil {
+		return nil, err
+	}
+	// Retrieve the row using the primary index.
+	snap, key, err := conn.primaryIndex(ctx, t, encPK)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Close()
+	valRef, err := snap.Get(ctx, key)
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil, dbsql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, err := valRef.Resolve()
+	if err == nil {
+		// The row is stored in the transaction using the key of the row itself.
+		rowKey := sql.MapKey(conn.engine.GetPrefix(), sql.RowPrefix, sql.EncodeID(sql.DatabaseID),
+			sql.EncodeID(t.ID()), sql.EncodeID(sql.PKIndexID), encPK)
+		err = conn.shared.verify(valRef.Tx(), &store.EntrySpec{
+			Key:      rowKey,
+			Metadata: valRef.KVMetadata(),
+			Value:    value,
+		})
+	}
+	if errors.Is(err, store.ErrCorruptedData) {
+		conn.log(ctx, common.LogLevelError, "verifying row failed", withErr(map[string]any{"table": table}, err))
+		return nil, fmt.Errorf("%w: %w", common.ErrVerificationFailed, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRow(t, value)
+}
+
+// primaryKey looks up a table and encodes the given values of its primary key.
+func (conn *immudbEmbedded) primaryKey(ctx context.Context, table string, pk []any) (*sql.Table, []byte, error) {
+	catalog, err := conn.engine.Catalog(ctx, conn.sqlTx)
+	if err != nil {
+		return nil, nil, err
+	}
+	t, err := catalog.GetTableByName(table)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkCols := t.PrimaryIndex().Cols()
+	if len(pk) != len(pkCols) {
+		return nil, nil, fmt.Errorf("%w: table %s has %d primary key columns, but %d values were given",
+			common.ErrPrimaryKeyMismatch, table, len(pkCols), len(pk))
+	}
+	var encPK []byte
+	for i, col := range pkCols {
+		value, err := driver.DefaultParameterConverter.ConvertValue(pk[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		encValue, _, err := sql.EncodeRawValueAsKey(value, col.Type(), col.MaxLen())
+		if err != nil {
+			return nil, nil, err
+		}
+		encPK = append(encPK, encValue...)
+	}
+	return t, encPK, nil
+}
+
+// primaryIndex returns a snapshot of the primary index of a table, which
+// includes all transactions committed so far, and the key of the row in it.
+func (conn *immudbEmbedded) primaryIndex(ctx context.Context, t *sql.Table, encPK []byte) (*store.Snapshot, []byte, error) {
+	st := conn.engine.GetStore()
+	indexPrefix := sql.MapKey(conn.engine.GetPrefix(), sql.MappedPrefix, sql.EncodeID(t.ID()), sql.EncodeID(sql.PKIndexID))
+	snap, err := st.SnapshotMustIncludeTxID(ctx, indexPrefix, st.LastCommittedTxID())
+	if err != nil {
+		return nil, nil, err
+	}
+	return snap, sql.MapKey(indexPrefix, "", encPK, encPK), nil
+}
+
+// decodeRow decodes the values of a row of a table.
+// Values of columns not stored in the row are NULL.
+func decodeRow(t *sql.Table, value []byte) (map[string]any, error) {
+	values := make(map[string]any, len(t.Cols()))
+	for _, col := range t.Cols() {
+		values[col.Name()] = nil
+	}
+	// The row is encoded as the number of columns
+	// followed by the id and the value of each column.
+	if len(value) < sql.EncLenLen {
+		return nil, store.ErrCorruptedData
+	}
+	count := int(binary.BigEndian.Uint32(value))
+	off := sql.EncLenLen
+	for i := 0; i < count; i++ {
+		if len(value) < off+sql.EncIDLen {
+			return nil, store.ErrCorruptedData
+		}
+		colID := binary.BigEndian.Uint32(value[off:])
+		off += sql.EncIDLen
+		col, err := t.GetColumnByID(colID)
+		// Skip columns, which have been dropped.
+		if errors.Is(err, sql.ErrColumnDoesNotExist) {
+			vlen, voff, err := sql.DecodeValueLength(value[off:])
+			if err != nil {
+				return nil, err
+			}
+			off += vlen + voff
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		val, n, err := sql.DecodeValue(value[off:], col.Type())
+		if err != nil {
+			return nil, err
+		}
+		off += n
+		values[col.Name()] = val.RawValue()
+	}
+	return values, nil
+}
+
+// verify checks that the entry is part of the transaction with the given id
+// and that the transaction is consistent with the last verified state of the store.
+// The first verification trusts the state of the store at that time.
+func (shared *sharedStore) verify(txID uint64, entry *store.EntrySpec) error {
+	st := shared.store
+	// Verify that the entry is included in the transaction.
+	tx := store.NewTx(st.MaxTxEntries(), st.MaxKeyLen())
+	err := st.ReadTx(txID, false, tx)
+	if err != nil {
+		return err
+	}
+	proof, err := tx.Proof(entry.Key)
+	if err != nil {
+		return err
+	}
+	hdr := tx.Header()
+	entrySpecDigest, err := store.EntrySpecDigestFor(hdr.Version)
+	if err != nil {
+		return err
+	}
+	if !store.VerifyInclusion(proof, entrySpecDigest(entry), hdr.Eh) {
+		return fmt.Errorf("%w: inclusion proof of transaction %d", store.ErrCorruptedData, txID)
+	}
+	// Verify that the transaction is consistent with the verified state.
+	shared.verifyLock.Lock()
+	defer shared.verifyLock.Unlock()
+	if shared.verified.txID == 0 {
+		// Trust on first use: without a previously verified state, the latest
+		// committed state is trusted. It is not persisted, so the state is
+		// trusted again after the store has been reopened.
+		shared.verified.txID, shared.verified.alh = st.CommittedAlh()
+	}
+	source := shared.verified
+	target := verifiedState{txID: hdr.ID, alh: hdr.Alh()}
+	if source.txID > target.txID {
+		source, target = target, source
+	}
+	if source.txID == target.txID {
+		if source.alh != target.alh {
+			return fmt.Errorf("%w: transaction %d differs from verified state", store.ErrCorruptedData, txID)
+		}
+		return nil
+	}
+	sourceHdr, err := st.ReadTxHeader(source.txID, false, false)
+	if err != nil {
+		return err
+	}
+	targetHdr, err := st.ReadTxHeader(target.txID, false, false)
+	if err != nil {
+		return err
+	}
+	dualProof, err := st.DualProof(sourceHdr, targetHdr)
+	if err != nil {
+		return err
+	}
+	if !store.VerifyDualProof(dualProof, source.txID, target.txID, source.alh, target.alh) {
+		return fmt.Errorf("%w: dual proof between transactions %d and %d", store.ErrCorruptedData, source.txID, target.txID)
+	}
+	// Advance the verified state.
+	shared.verified = target
+	return nil
+}
@@ -0,0 +1,49 @@
+package immusql
+
+import "github.com/tauu/immusql/common"
+
+// Error is an error reported by immudb together with a code identifying
+// its kind. Errors returned by the driver can be inspected using errors.As
+//
+//	var immuErr *immusql.Error
+//	if errors.As(err, &immuErr) && immuErr.Code == immusql.CodeUniqueViolation {
+//		...
+//	}
+//
+// or compared with the error of a kind using errors.Is
+//
+//	if errors.Is(err, immusql.ErrUniqueViolation) {
+//		...
+//	}
+//
+// The original error of immudb is still available using errors.Unwrap.
+type Error = common.Error
+
+// ErrorCode identifies the kind of an error reported by immudb.
+type ErrorCode = common.ErrorCode
+
+// Define the codes of errors reported by immudb.
+const (
+	CodeUniqueViolation  = common.CodeUniqueViolation
+	CodeTableNotFound    = common.CodeTableNotFound
+	CodeColumnNotFound   = common.CodeColumnNotFound
+	CodeTxConflict       = common.CodeTxConflict
+	CodeNotNullViolation = common.CodeNotNullViolation
+	CodeCheckViolation   = common.CodeCheckViolation
+	CodeSyntaxError      = common.CodeSyntaxError
+	CodePermissionDenied = common.CodePermissionDenied
+	CodeSessionExpired   = common.CodeSessionExpired
+)
+
+// Define an error for each kind, which can be used with errors.Is.
+var (
+	ErrUniqueViolation  = &Error{Code: CodeUniqueViolation}
+	ErrTableNotFound    = &Error{Code: CodeTableNotFound}
+	ErrColumnNotFound   = &Error{Code: CodeColumnNotFound}
+	ErrTxConflict       = &Error{Code: CodeTxConflict}
+	ErrNotNullViolation = &Error{Code: CodeNotNullViolation}
+	ErrCheckViolation   = &Error{Code: CodeCheckViolation}
+	ErrSyntaxError      = &Error{Code: CodeSyntaxError}
+	ErrPermissionDenied = &Error{Code: CodePermissionDenied}
+	ErrSessionExpired   = &Error{Code: CodeSessionExpired}
+)
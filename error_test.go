@@ -0,0 +1,139 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/server/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertErrorCode checks that err is an Error with the given code.
+func assertErrorCode(t *testing.T, err error, code ErrorCode, msg string) {
+	var immuErr *Error
+	if assert.ErrorAs(t, err, &immuErr, msg) {
+		assert.Equal(t, code, immuErr.Code, msg)
+		assert.True(t, errors.Is(err, &Error{Code: code}), "the error should match its kind using errors.Is: %s", msg)
+		assert.NotNil(t, errors.Unwrap(immuErr), "the error of immudb should be wrapped: %s", msg)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER, name VARCHAR NOT NULL, age INTEGER, CHECK (age >= 0), PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO test(id, name, age) VALUES(1, 'Maria', 30)")
+		require.NoError(t, err, "inserting data should not fail")
+
+		tests := []struct {
+			name  string
+			query string
+			code  ErrorCode
+		}{
+			{"duplicate primary key", "INSERT INTO test(id, name, age) VALUES(1, 'Marc', 20)", CodeUniqueViolation},
+			{"missing table in insert", "INSERT INTO missing(id) VALUES(1)", CodeTableNotFound},
+			{"missing column in insert", "INSERT INTO test(id, name, missing) VALUES(2, 'Marc', 1)", CodeColumnNotFound},
+			{"null value", "INSERT INTO test(id, name, age) VALUES(2, NULL, 20)", CodeNotNullViolation},
+			{"check constraint", "INSERT INTO test(id, name, age) VALUES(2, 'Marc', -1)", CodeCheckViolation},
+			{"syntax error", "INSERT INTO test(id name) VALUES(2, 'Marc')", CodeSyntaxError},
+		}
+		for _, test := range tests {
+			_, err := db.Exec(test.query)
+			assertErrorCode(t, err, test.code, test.name)
+		}
+
+		// Errors are also reported for queries.
+		queries := []struct {
+			name  string
+			query string
+			code  ErrorCode
+		}{
+			{"missing table in query", "SELECT * FROM missing", CodeTableNotFound},
+			{"missing column in query", "SELECT missing FROM test", CodeColumnNotFound},
+			{"syntax error in query", "SELEC * FROM test", CodeSyntaxError},
+		}
+		for _, test := range queries {
+			rows, err := db.Query(test.query)
+			if err == nil {
+				for rows.Next() {
+				}
+				err = rows.Err()
+				rows.Close()
+			}
+			assertErrorCode(t, err, test.code, test.name)
+		}
+
+		// Errors of statements in a transaction are reported.
+		tx, err := db.Begin()
+		require.NoError(t, err, "beginning a transaction should not fail")
+		_, err = tx.Exec("INSERT INTO test(id, name, age) VALUES(1, 'Marc', 20)")
+		assertErrorCode(t, err, CodeUniqueViolation, "duplicate primary key in a transaction")
+		tx.Rollback()
+
+		// Conflicts are reported when committing a transaction.
+		tx, err = db.Begin()
+		require.NoError(t, err, "beginning a transaction should not fail")
+		var age int64
+		require.NoError(t, tx.QueryRow("SELECT age FROM test WHERE id = 1").Scan(&age), "querying data should not fail")
+		_, err = db.Exec("UPDATE test SET age = 31 WHERE id = 1")
+		require.NoError(t, err, "updating data should not fail")
+		_, err = tx.Exec("UPDATE test SET age = ? WHERE id = 1", age+2)
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
+		assertErrorCode(t, err, CodeTxConflict, "conflicting transactions")
+		assert.ErrorIs(t, err, ErrTxConflict, "the conflict should match ErrTxConflict")
+	})
+}
+
+func TestErrorPermissionDeniedClient(t *testing.T) {
+	port, err := startServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the server should not fail")
+	admin, err := sql.Open("immudb", clientDSN(t, port, nil))
+	require.NoError(t, err, "opening a connection should not fail")
+	defer admin.Close()
+	_, err = admin.Exec("CREATE TABLE test(id INTEGER, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	_, err = admin.Exec("CREATE USER reader WITH PASSWORD 'Reader123!' READ")
+	require.NoError(t, err, "creating a user should not fail")
+
+	dsn, err := url.Parse(clientDSN(t, port, nil))
+	require.NoError(t, err, "parsing the dsn should not fail")
+	dsn.User = url.UserPassword("reader", "Reader123!")
+	reader, err := sql.Open("immudb", dsn.String())
+	require.NoError(t, err, "opening a connection should not fail")
+	defer reader.Close()
+	_, err = reader.Exec("INSERT INTO test(id) VALUES(1)")
+	assertErrorCode(t, err, CodePermissionDenied, "inserting data without permission")
+	assert.ErrorIs(t, err, ErrPermissionDenied, "the error should match ErrPermissionDenied")
+}
+
+func TestErrorSessionExpiredClient(t *testing.T) {
+	// The server closes sessions shortly after they have been created.
+	opts := testServerOptions(t).WithSessionOptions(sessions.DefaultOptions().
+		WithSessionGuardCheckInterval(50 * time.Millisecond).
+		WithMaxSessionAgeTime(200 * time.Millisecond))
+	port, err := startServer(t, opts)
+	require.NoError(t, err, "starting the server should not fail")
+	db, err := sql.Open("immudb", clientDSN(t, port, nil))
+	require.NoError(t, err, "opening a connection should not fail")
+	defer db.Close()
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err, "retrieving a connection should not fail")
+	defer conn.Close()
+	tx, err := conn.BeginTx(context.Background(), nil)
+	require.NoError(t, err, "beginning a transaction should not fail")
+	time.Sleep(500 * time.Millisecond)
+	_, err = tx.Exec("CREATE TABLE test(id INTEGER, PRIMARY KEY id)")
+	assertErrorCode(t, err, CodeSessionExpired, "executing a statement after the session expired")
+	assert.ErrorIs(t, err, ErrSessionExpired, "the error should match ErrSessionExpired")
+	// The transaction has to be completed before the connection can be closed.
+	tx.Rollback()
+}
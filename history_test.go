@@ -0,0 +1,173 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// history calls History on a connection of the database.
+func history(t *testing.T, db *sql.DB, table string, pk ...any) ([]Revision, error) {
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err, "retrieving an actual database connection failed")
+	defer conn.Close()
+	var revisions []Revision
+	err = conn.Raw(func(driverConn any) error {
+		immuConn, ok := driverConn.(ImmuDBconn)
+		require.True(t, ok, "driver object of database connection does not satisfiy ImmuDBconn interface")
+		var err error
+		revisions, err = immuConn.History(context.Background(), table, pk...)
+		return err
+	})
+	return revisions, err
+}
+
+func TestHistory(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		start := time.Now().Add(-time.Second)
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER, name VARCHAR, age INTEGER, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		// Another table with the same primary key must not be part of the history.
+		_, err = db.Exec("CREATE TABLE IF NOT EXISTS other(id INTEGER, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		statements := []string{
+			"INSERT INTO test(id, name, age) VALUES(1, 'Maria', 30)",
+			"INSERT INTO other(id, name) VALUES(1, 'Other')",
+			"INSERT INTO test(id, name, age) VALUES(2, 'Marc', 40)",
+			"UPDATE test SET name = 'Maria Magdalena' WHERE id = 1",
+			"UPDATE test SET age = 31 WHERE id = 1",
+			"DELETE FROM other WHERE id = 1",
+			"DELETE FROM test WHERE id = 1",
+			"INSERT INTO test(id, name) VALUES(1, 'Mia')",
+		}
+		for _, statement := range statements {
+			_, err = db.Exec(statement)
+			require.NoError(t, err, "executing %s should not fail", statement)
+		}
+
+		revisions, err := history(t, db, "test", 1)
+		require.NoError(t, err, "retrieving the history of a row should not fail")
+		expected := []struct {
+			deleted bool
+			values  map[string]any
+		}{
+			{false, map[string]any{"id": int64(1), "name": "Maria", "age": int64(30)}},
+			{false, map[string]any{"id": int64(1), "name": "Maria Magdalena", "age": int64(30)}},
+			{false, map[string]any{"id": int64(1), "name": "Maria Magdalena", "age": int64(31)}},
+			{true, map[string]any{"id": int64(1), "name": "Maria Magdalena", "age": int64(31)}},
+			{false, map[string]any{"id": int64(1), "name": "Mia", "age": nil}},
+		}
+		require.Len(t, revisions, len(expected), "the history should contain every revision of the row")
+		for i, revision := range revisions {
+			assert.Equal(t, expected[i].deleted, revision.Deleted, "revision %d has an unexpected deleted flag", i+1)
+			assert.Equal(t, expected[i].values, revision.Values, "revision %d has unexpected values", i+1)
+			assert.False(t, revision.Timestamp.Before(start), "revision %d has a timestamp before the test", i+1)
+			assert.False(t, revision.Timestamp.After(time.Now()), "revision %d has a timestamp in the future", i+1)
+			if i > 0 {
+				assert.Greater(t, revision.TxID, revisions[i-1].TxID, "revision %d should be stored by a later transaction", i+1)
+			}
+		}
+
+		// The revisions can be read using their transactions.
+		for i, revision := range revisions {
+			var name string
+			err := db.QueryRowContext(WithTxSnapshot(context.Background(), revision.TxID), "SELECT name FROM test WHERE id = 1").Scan(&name)
+			if revision.Deleted {
+				assert.ErrorIs(t, err, sql.ErrNoRows, "the row should not exist after revision %d", i+1)
+				continue
+			}
+			require.NoError(t, err, "querying revision %d should not fail", i+1)
+			assert.Equal(t, revision.Values["name"], name, "the snapshot of revision %d has an unexpected name", i+1)
+		}
+
+		// Rows, which are not modified, have a single revision.
+		revisions, err = history(t, db, "test", 2)
+		require.NoError(t, err, "retrieving the history of a row should not fail")
+		require.Len(t, revisions, 1, "the history should contain the inserted row")
+		assert.Equal(t, "Marc", revisions[0].Values["name"], "the revision has an unexpected name")
+
+		// Rows of other tables have their own history.
+		revisions, err = history(t, db, "other", 1)
+		require.NoError(t, err, "retrieving the history of a row should not fail")
+		require.Len(t, revisions, 2, "the history should contain the inserted and deleted row")
+		assert.False(t, revisions[0].Deleted, "the first revision should not be deleted")
+		assert.True(t, revisions[1].Deleted, "the second revision should be deleted")
+
+		// Rows, which never existed, have no history.
+		revisions, err = history(t, db, "test", 10)
+		require.NoError(t, err, "retrieving the history of a missing row should not fail")
+		assert.Empty(t, revisions, "a missing row should not have any revisions")
+		_, err = history(t, db, "test", 1, "Maria")
+		assert.ErrorIs(t, err, ErrPrimaryKeyMismatch, "retrieving the history with too many primary key values should fail")
+		_, err = history(t, db, "test WHERE 1 = 1 OR id", 1)
+		assert.Error(t, err, "retrieving the history of a table with an invalid name should fail")
+	})
+}
+
+func TestHistoryCompositeKey(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(name VARCHAR[32], id INTEGER, age INTEGER, PRIMARY KEY (id, name))")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO test(id, name, age) VALUES(1, 'Maria', 30), (1, 'Marc', 40)")
+		require.NoError(t, err, "inserting data should not fail")
+		_, err = db.Exec("UPDATE test SET age = 41 WHERE id = 1 AND name = 'Marc'")
+		require.NoError(t, err, "updating data should not fail")
+		revisions, err := history(t, db, "test", 1, "Marc")
+		require.NoError(t, err, "retrieving the history of a row should not fail")
+		require.Len(t, revisions, 2, "the history should contain every revision of the row")
+		assert.Equal(t, int64(40), revisions[0].Values["age"], "the first revision has an unexpected age")
+		assert.Equal(t, int64(41), revisions[1].Values["age"], "the second revision has an unexpected age")
+	})
+}
+
+func TestHistoryManyTransactions(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		// The revisions of the row are separated by many other transactions.
+		exec := func(statement string, args ...any) {
+			_, err := db.Exec(statement, args...)
+			require.NoError(t, err, "executing %s should not fail", statement)
+		}
+		exec("INSERT INTO test(id, name) VALUES(1, 'Maria')")
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 50; j++ {
+				exec("UPSERT INTO test(id, name) VALUES(?, 'other')", 2+j)
+			}
+			switch i {
+			case 0:
+				exec("UPDATE test SET name = 'Maria Magdalena' WHERE id = 1")
+			case 1:
+				exec("DELETE FROM test WHERE id = 1")
+			}
+		}
+
+		revisions, err := history(t, db, "test", 1)
+		require.NoError(t, err, "retrieving the history of a row should not fail")
+		require.Len(t, revisions, 3, "the history should contain every revision of the row")
+		assert.Equal(t, []bool{false, false, true}, []bool{revisions[0].Deleted, revisions[1].Deleted, revisions[2].Deleted},
+			"only the last revision should be deleted")
+		// The row is modified by each transaction of a revision.
+		count := func(txID uint64, name any) int64 {
+			var count int64
+			err := db.QueryRowContext(WithTxSnapshot(context.Background(), txID),
+				"SELECT COUNT(*) FROM test WHERE id = 1 AND name = ?", name).Scan(&count)
+			require.NoError(t, err, "querying the state at transaction %d should not fail", txID)
+			return count
+		}
+		for i, revision := range revisions {
+			stored := int64(1)
+			if revision.Deleted {
+				stored = 0
+			}
+			assert.Equal(t, 1-stored, count(revision.TxID-1, revision.Values["name"]),
+				"revision %d should not exist before transaction %d", i+1, revision.TxID)
+			assert.Equal(t, stored, count(revision.TxID, revision.Values["name"]),
+				"revision %d should have been stored by transaction %d", i+1, revision.TxID)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package immusql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON stores a value of type T in a JSON column. It can be used as a
+// parameter of a statement and as a destination when scanning rows.
+//
+//	var profile immusql.JSON[Profile]
+//	err := db.QueryRow("SELECT profile FROM users WHERE id = ?", id).Scan(&profile)
+//
+// Scanning NULL sets V to the zero value of T. Maps and slices are also
+// converted to JSON, when they are used as a parameter. Structs are not
+// converted implicitly and have to be wrapped in JSON.
+type JSON[T any] struct {
+	V T
+}
+
+// Scan decodes the JSON text of a value read from the database into V.
+func (j *JSON[T]) Scan(src any) error {
+	var zero T
+	j.V = zero
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(src, &j.V)
+	case string:
+		return json.Unmarshal([]byte(src), &j.V)
+	default:
+		return fmt.Errorf("scanning %T into JSON is not supported", src)
+	}
+}
+
+// Value encodes V as JSON text, which is stored as JSON value by immudb.
+func (j JSON[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
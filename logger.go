@@ -2,45 +2,153 @@ package immusql
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/tauu/immusql/common"
 )
 
 // LogLevel defines the logging level to be used by a logger.
-type LogLevel int
+type LogLevel = common.LogLevel
 
 // Define log levels. The default level is debug.
 const (
-	LogLevelDebug LogLevel = iota
-	LogLevelInfo
-	LogLevelWarn
-	LogLevelError
-	LogLevelFatal
-	LogLevelPanic
-	LogLevelNone
-
-	LogLevelTrace LogLevel = -1
+	LogLevelDebug = common.LogLevelDebug
+	LogLevelInfo  = common.LogLevelInfo
+	LogLevelWarn  = common.LogLevelWarn
+	LogLevelError = common.LogLevelError
+	LogLevelFatal = common.LogLevelFatal
+	LogLevelPanic = common.LogLevelPanic
+	LogLevelNone  = common.LogLevelNone
+
+	LogLevelTrace = common.LogLevelTrace
 )
 
 // Logger is the interface for logging debug messages of the driver.
+// Additional information about an event is appended to the message,
+// unless the logger implements DataLogger.
 type Logger interface {
 	Log(ctx context.Context, level LogLevel, msg string)
 }
 
+// DataLogger is a Logger receiving additional structured information about
+// an event, e.g. the sql text and the duration of a query, as data.
+// LogData is called instead of Log for loggers implementing it.
+type DataLogger interface {
+	Logger
+	LogData(ctx context.Context, level LogLevel, msg string, data map[string]any)
+}
+
+// LogPolicy defines which information about queries is logged.
+// The zero value logs the sql text of all queries
+// at debug level without any argument values.
+type LogPolicy = common.LogPolicy
+
+// LogArgsMode defines how the arguments of a query are logged.
+type LogArgsMode = common.LogArgsMode
+
+// Define the modes for logging arguments of queries.
+const (
+	LogArgsOmit     = common.LogArgsOmit
+	LogArgsHash     = common.LogArgsHash
+	LogArgsTruncate = common.LogArgsTruncate
+)
+
+// WithoutQueryLogging returns a context for which the executed queries are not logged.
+// It can be used to prevent sensitive queries from appearing in the logs.
+func WithoutQueryLogging(ctx context.Context) context.Context {
+	return common.WithoutQueryLog(ctx)
+}
+
+// driverLogger is the logger used by connectors without their own logger.
+var driverLogger Logger = defaultLogger{level: LogLevelNone}
+
+// driverLoggerLock guards access to driverLogger.
+var driverLoggerLock sync.RWMutex
+
+// SetLogger sets the logger used by all connections,
+// whose configuration does not specify a logger.
+// It only affects connections opened after calling it.
+func SetLogger(logger Logger) {
+	driverLoggerLock.Lock()
+	defer driverLoggerLock.Unlock()
+	if logger == nil {
+		logger = defaultLogger{level: LogLevelNone}
+	}
+	driverLogger = logger
+}
+
+// getLogger retrieves the logger set by SetLogger.
+func getLogger() Logger {
+	driverLoggerLock.RLock()
+	defer driverLoggerLock.RUnlock()
+	return driverLogger
+}
+
+// NewLogger creates a logger writing all messages with at least
+// the given level to the standard logger of the log package.
+func NewLogger(level LogLevel) DataLogger {
+	return defaultLogger{level: level}
+}
+
+// adaptedLogger forwards the messages of the connections to a Logger.
+type adaptedLogger struct {
+	logger Logger
+}
+
+// adaptLogger converts a Logger into the logger used by the connections.
+func adaptLogger(logger Logger) common.Logger {
+	return adaptedLogger{logger: logger}
+}
+
+func (al adaptedLogger) Log(ctx context.Context, level LogLevel, msg string, data map[string]any) {
+	if dataLogger, ok := al.logger.(DataLogger); ok {
+		dataLogger.LogData(ctx, level, msg, data)
+		return
+	}
+	al.logger.Log(ctx, level, msg+formatLogData(data))
+}
+
+// formatLogData formats the data of a message sorted by key
+// as a list of key=value pairs, which can be appended to the message.
+func formatLogData(data map[string]any) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var fields strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&fields, " %s=%v", key, data[key])
+	}
+	return fields.String()
+}
+
 // defaultLogger is the default logger used if no custom logger has been set.
+// By default it does not log any messages.
 type defaultLogger struct {
 	level LogLevel
 }
 
 func (dl defaultLogger) Log(ctx context.Context, level LogLevel, msg string) {
 	// Only log the message if the level of the message is above the level of the logger.
-	if level < dl.level {
+	if level < dl.level || level == LogLevelNone {
 		return
 	}
 	levelName := logLevelName(level)
 	log.Printf("%v %s: %s", time.Now(), levelName, msg)
 }
 
+func (dl defaultLogger) LogData(ctx context.Context, level LogLevel, msg string, data map[string]any) {
+	// Append the data sorted by key to the message.
+	dl.Log(ctx, level, msg+formatLogData(data))
+}
+
 // logLevelName creates a printable name for a loglevel.
 func logLevelName(level LogLevel) (name string) {
 	switch level {
@@ -63,3 +171,57 @@ func logLevelName(level LogLevel) (name string) {
 	}
 	return
 }
+
+// slogLogger forwards log messages to a slog logger.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a logger forwarding all messages to a slog logger.
+// The data of a message is added as attributes to the slog record.
+func NewSlogLogger(logger *slog.Logger) DataLogger {
+	return slogLogger{logger: logger}
+}
+
+func (sl slogLogger) Log(ctx context.Context, level LogLevel, msg string) {
+	sl.LogData(ctx, level, msg, nil)
+}
+
+func (sl slogLogger) LogData(ctx context.Context, level LogLevel, msg string, data map[string]any) {
+	if level == LogLevelNone {
+		return
+	}
+	// Add the data sorted by key as attributes.
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, key := range keys {
+		attrs = append(attrs, slog.Any(key, data[key]))
+	}
+	sl.logger.LogAttrs(ctx, slogLevel(level), msg, attrs...)
+}
+
+// slogLevel converts a log level to the corresponding slog level.
+// slog does not define levels for trace, fatal and panic messages.
+// They are mapped to levels below debug and above error respectively.
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelTrace:
+		return slog.LevelDebug - 4
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelInfo:
+		return slog.LevelInfo
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	case LogLevelFatal:
+		return slog.LevelError + 4
+	default:
+		return slog.LevelError + 8
+	}
+}
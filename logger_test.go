@@ -0,0 +1,280 @@
+package immusql
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logEntry is a message received by the testLogger.
+type logEntry struct {
+	level LogLevel
+	msg   string
+	data  map[string]any
+}
+
+// testLogger records all received log messages.
+type testLogger struct {
+	lock    sync.Mutex
+	entries []logEntry
+}
+
+func (l *testLogger) Log(ctx context.Context, level LogLevel, msg string) {
+	l.LogData(ctx, level, msg, nil)
+}
+
+func (l *testLogger) LogData(ctx context.Context, level LogLevel, msg string, data map[string]any) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, data: data})
+}
+
+// find returns all recorded entries with the given message.
+func (l *testLogger) find(msg string) []logEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	var res []logEntry
+	for _, entry := range l.entries {
+		if entry.msg == msg {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+// testConfigs returns configurations for an embedded database
+// and a client connection to a test server.
+func testConfigs(t *testing.T) map[string]Config {
+	port, err := startServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the immudb server should not fail")
+	return map[string]Config{
+		"embedded": {
+			Embedded: true,
+			Path:     t.TempDir(),
+		},
+		"client": {
+			User:          "immudb",
+			Pass:          "immudb",
+			Port:          port,
+			ClientOptions: client.DefaultOptions().WithDir(t.TempDir()),
+		},
+	}
+}
+
+// openConfig opens a database using the given configuration.
+func openConfig(t *testing.T, config Config) *sql.DB {
+	connector, err := NewConnector(config)
+	require.NoError(t, err, "creating a connector should not fail")
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLogger(t *testing.T) {
+	for name, config := range testConfigs(t) {
+		t.Run(name, func(t *testing.T) {
+			logger := &testLogger{}
+			config.Logger = logger
+			db := openConfig(t, config)
+			_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+			require.NoError(t, err, "creating a table should not fail")
+			assert.NotEmpty(t, logger.find("connection opened"), "opening the connection should be logged")
+
+			// Executed statements are logged with their sql and duration.
+			entries := logger.find("statement executed")
+			if assert.NotEmpty(t, entries, "executing a statement should be logged") {
+				entry := entries[len(entries)-1]
+				assert.Equal(t, LogLevelDebug, entry.level, "a successful statement should be logged at debug level")
+				assert.Contains(t, entry.data["sql"], "CREATE TABLE", "the sql of the statement should be logged")
+				assert.Contains(t, entry.data, "duration", "the duration of the statement should be logged")
+			}
+
+			// Transactions are logged.
+			tx, err := db.Begin()
+			require.NoError(t, err, "beginning a transaction should not fail")
+			_, err = tx.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+			require.NoError(t, err, "inserting data should not fail")
+			require.NoError(t, tx.Commit(), "committing the transaction should not fail")
+			tx, err = db.Begin()
+			require.NoError(t, err, "beginning a transaction should not fail")
+			require.NoError(t, tx.Rollback(), "rolling back the transaction should not fail")
+			// Committing a read only transaction rolls it back.
+			tx, err = db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+			require.NoError(t, err, "beginning a read only transaction should not fail")
+			require.NoError(t, tx.Commit(), "committing the read only transaction should not fail")
+			assert.Len(t, logger.find("transaction started"), 3, "starting a transaction should be logged")
+			assert.Len(t, logger.find("transaction committed"), 1, "committing a transaction should be logged")
+			assert.Len(t, logger.find("transaction rolled back"), 1, "rolling back a transaction should be logged")
+			assert.Len(t, logger.find("read only transaction rolled back"), 1, "rolling back a read only transaction should be logged")
+
+			// Queries are logged and failing queries are logged as errors.
+			rows, err := db.Query("SELECT name FROM test")
+			require.NoError(t, err, "querying data should not fail")
+			rows.Close()
+			assert.NotEmpty(t, logger.find("query executed"), "executing a query should be logged")
+			_, err = db.Query("SELECT name FROM unknown")
+			require.Error(t, err, "querying an unknown table should fail")
+			var failed bool
+			for _, entry := range logger.find("query executed") {
+				if entry.level == LogLevelError && entry.data["err"] != nil {
+					failed = true
+				}
+			}
+			assert.True(t, failed, "a failing query should be logged as error")
+
+			// Closing the database closes the connections.
+			require.NoError(t, db.Close(), "closing the database should not fail")
+			assert.NotEmpty(t, logger.find("connection closed"), "closing the connection should be logged")
+		})
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	logger := &testLogger{}
+	SetLogger(logger)
+	defer SetLogger(nil)
+	// Connections without their own logger use the driver logger.
+	db, err := openConnection(t)
+	require.NoError(t, err, "opening the connection should not fail")
+	defer db.Close()
+	assert.NotEmpty(t, logger.find("connection opened"), "the driver logger should receive messages")
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := NewSlogLogger(slog.New(handler))
+	logger.LogData(context.Background(), LogLevelDebug, "query executed", map[string]any{"sql": "SELECT 1"})
+	logger.Log(context.Background(), LogLevelTrace, "column type retrieved")
+	output := buf.String()
+	assert.Contains(t, output, "level=DEBUG", "the level should be converted")
+	assert.Contains(t, output, `msg="query executed"`, "the message should be written")
+	assert.Contains(t, output, `sql="SELECT 1"`, "the data should be written as attributes")
+	assert.NotContains(t, output, "column type retrieved", "trace messages should be below the debug level")
+}
+
+// plainLogger only implements Logger without receiving the data of a message.
+type plainLogger struct {
+	lock     sync.Mutex
+	messages []string
+}
+
+func (l *plainLogger) Log(ctx context.Context, level LogLevel, msg string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.messages = append(l.messages, msg)
+}
+
+func TestPlainLogger(t *testing.T) {
+	logger := &plainLogger{}
+	db := openConfig(t, Config{Embedded: true, Path: t.TempDir(), Logger: logger})
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	// The data of a message is appended to it.
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	found := false
+	for _, msg := range logger.messages {
+		if strings.HasPrefix(msg, "statement executed duration=") && strings.Contains(msg, " sql=CREATE TABLE") {
+			found = true
+		}
+	}
+	assert.True(t, found, "the data should be appended to the message: %v", logger.messages)
+}
+
+// lastEntry returns the last recorded entry with the given message.
+func (l *testLogger) lastEntry(t *testing.T, msg string) logEntry {
+	entries := l.find(msg)
+	require.NotEmpty(t, entries, "the message %s should have been logged", msg)
+	return entries[len(entries)-1]
+}
+
+func TestLogPolicy(t *testing.T) {
+	for name, config := range testConfigs(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arguments are omitted by default.
+			logger := &testLogger{}
+			config.Logger = logger
+			db := openConfig(t, config)
+			_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+			require.NoError(t, err, "creating a table should not fail")
+			_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Margarethe")
+			require.NoError(t, err, "inserting data should not fail")
+			entry := logger.lastEntry(t, "statement executed")
+			assert.Equal(t, "INSERT INTO test(name) VALUES(?)", entry.data["sql"], "the sql should be logged")
+			assert.NotContains(t, entry.data, "args", "the arguments should not be logged by default")
+			assert.Equal(t, LogLevelDebug, entry.level, "fast queries should be logged at debug level")
+
+			// Arguments can be hashed.
+			config.LogPolicy = LogPolicy{Args: LogArgsHash}
+			db = openConfig(t, config)
+			_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Margarethe")
+			require.NoError(t, err, "inserting data should not fail")
+			entry = logger.lastEntry(t, "statement executed")
+			if assert.Len(t, entry.data["args"], 1, "the hashed argument should be logged") {
+				hashed := entry.data["args"].([]string)[0]
+				assert.NotContains(t, hashed, "Margarethe", "the argument value should not be logged")
+				assert.Len(t, hashed, 16, "the argument should be logged as hash")
+				sum := sha256.Sum256([]byte("Margarethe"))
+				assert.NotEqual(t, hex.EncodeToString(sum[:8]), hashed, "the argument should not be hashed without a key")
+			}
+
+			// Arguments are hashed using the given key.
+			key := []byte("secret")
+			config.LogPolicy = LogPolicy{Args: LogArgsHash, HashKey: key}
+			db = openConfig(t, config)
+			_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Margarethe")
+			require.NoError(t, err, "inserting data should not fail")
+			entry = logger.lastEntry(t, "statement executed")
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte("Margarethe"))
+			assert.Equal(t, []string{hex.EncodeToString(mac.Sum(nil)[:8])}, entry.data["args"], "the argument should be hashed using the key")
+
+			// Arguments can be truncated.
+			config.LogPolicy = LogPolicy{Args: LogArgsTruncate, TruncateLength: 3}
+			db = openConfig(t, config)
+			rows, err := db.Query("SELECT name FROM test WHERE name = ?", "Margarethe")
+			require.NoError(t, err, "querying data should not fail")
+			rows.Close()
+			entry = logger.lastEntry(t, "query executed")
+			assert.Equal(t, []string{"Mar..."}, entry.data["args"], "the argument should be truncated")
+
+			// Slow queries are logged as warning.
+			config.LogPolicy = LogPolicy{SlowQueryThreshold: time.Nanosecond}
+			db = openConfig(t, config)
+			rows, err = db.Query("SELECT name FROM test")
+			require.NoError(t, err, "querying data should not fail")
+			rows.Close()
+			entry = logger.lastEntry(t, "query executed")
+			assert.Equal(t, LogLevelWarn, entry.level, "slow queries should be logged at warn level")
+			assert.Equal(t, true, entry.data["slow"], "slow queries should be marked")
+
+			// Logging can be disabled for single queries.
+			count := len(logger.find("query executed"))
+			ctx := WithoutQueryLogging(context.Background())
+			rows, err = db.QueryContext(ctx, "SELECT name FROM test WHERE name = ?", "Margarethe")
+			require.NoError(t, err, "querying data should not fail")
+			rows.Close()
+			_, err = db.ExecContext(ctx, "INSERT INTO test(name) VALUES(?)", "Margarethe")
+			require.NoError(t, err, "inserting data should not fail")
+			_, err = db.ExecContext(ctx, "INSERT INTO unknown(name) VALUES(?)", "Margarethe")
+			require.Error(t, err, "inserting into an unknown table should fail")
+			assert.Len(t, logger.find("query executed"), count, "queries with disabled logging should not be logged")
+			for _, entry := range logger.find("statement executed") {
+				assert.NotContains(t, entry.data["sql"], "unknown", "statements with disabled logging should not be logged")
+			}
+		})
+	}
+}
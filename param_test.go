@@ -0,0 +1,127 @@
+package immusql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Named types, which are converted according to their underlying type.
+type (
+	paramInt    int
+	paramBool   bool
+	paramString string
+	paramBytes  []byte
+)
+
+// paramValuer is a custom driver.Valuer returning a value,
+// which is not a driver.Value itself.
+type paramValuer struct {
+	n int32
+}
+
+func (v paramValuer) Value() (driver.Value, error) {
+	return v.n, nil
+}
+
+func TestParamConversion(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, i INTEGER, f FLOAT, b BOOLEAN, s VARCHAR, bs BLOB, ts TIMESTAMP, u UUID, j JSON, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+
+		name := "Maria"
+		age := 30
+		id := uuid.New()
+		zone := time.FixedZone("UTC+2", 2*60*60)
+		date := time.Date(2024, 5, 17, 10, 30, 0, 123456789, zone)
+		tests := []struct {
+			name     string
+			column   string
+			param    any
+			expected any
+		}{
+			{"int", "i", 42, int64(42)},
+			{"int8", "i", int8(-8), int64(-8)},
+			{"int16", "i", int16(-16), int64(-16)},
+			{"int32", "i", int32(-32), int64(-32)},
+			{"int64", "i", int64(math.MinInt64), int64(math.MinInt64)},
+			{"uint", "i", uint(7), int64(7)},
+			{"uint8", "i", uint8(8), int64(8)},
+			{"uint16", "i", uint16(16), int64(16)},
+			{"uint32", "i", uint32(math.MaxUint32), int64(math.MaxUint32)},
+			{"uint64", "i", uint64(math.MaxInt64), int64(math.MaxInt64)},
+			{"named int", "i", paramInt(3), int64(3)},
+			{"float32", "f", float32(1.5), 1.5},
+			{"float64", "f", 1.25, 1.25},
+			{"bool", "b", true, true},
+			{"named bool", "b", paramBool(true), true},
+			{"string", "s", "Marc", "Marc"},
+			{"named string", "s", paramString("Jose"), "Jose"},
+			{"bytes", "bs", []byte{1, 2}, []byte{1, 2}},
+			{"named bytes", "bs", paramBytes{3, 4}, []byte{3, 4}},
+			{"byte array", "bs", [3]byte{5, 6, 7}, []byte{5, 6, 7}},
+			{"time", "ts", date, date.UTC().Truncate(time.Microsecond)},
+			{"uuid", "u", id, id.String()},
+			{"uuid bytes", "u", [16]byte(id), id.String()},
+			{"string pointer", "s", &name, name},
+			{"int pointer", "i", &age, int64(age)},
+			{"nil pointer", "s", (*string)(nil), nil},
+			{"nil", "s", nil, nil},
+			{"valuer", "i", paramValuer{n: 12}, int64(12)},
+			{"null string", "s", sql.NullString{}, nil},
+			{"valid null int", "i", sql.NullInt64{Int64: 9, Valid: true}, int64(9)},
+			{"json", "j", json.RawMessage(`{"a":1}`), []byte(`{"a":1}`)},
+			{"marshaler", "j", jsonMarshaler{jsonPerson{Name: "Ana", Age: 25}}, []byte(`{"age":25,"name":"Ana"}`)},
+			{"wrapped map", "j", JSON[map[string]int]{V: map[string]int{"b": 2}}, []byte(`{"b":2}`)},
+			{"map", "j", map[string]int{"c": 3}, []byte(`{"c":3}`)},
+			{"slice", "j", []string{"a", "b"}, []byte(`["a","b"]`)},
+			{"array", "j", [2]int{1, 2}, []byte(`[1,2]`)},
+			{"nil map", "j", map[string]int(nil), nil},
+			{"nil slice", "j", []string(nil), nil},
+			{"time pointer", "ts", &date, date.UTC().Truncate(time.Microsecond)},
+		}
+		for _, test := range tests {
+			res, err := db.Exec("INSERT INTO test("+test.column+") VALUES(?)", test.param)
+			if !assert.NoError(t, err, "inserting a parameter should not fail: %s", test.name) {
+				continue
+			}
+			rowID, err := res.LastInsertId()
+			require.NoError(t, err, "retrieving the id of the inserted row should not fail: %s", test.name)
+			var value any
+			err = db.QueryRow("SELECT "+test.column+" FROM test WHERE id = ?", rowID).Scan(&value)
+			require.NoError(t, err, "querying the inserted value should not fail: %s", test.name)
+			if expected, ok := test.expected.(time.Time); ok {
+				if assert.IsType(t, time.Time{}, value, "the inserted value should be read as time: %s", test.name) {
+					assert.True(t, expected.Equal(value.(time.Time)), "the inserted value %v differs from %v: %s", value, expected, test.name)
+				}
+				continue
+			}
+			assert.Equal(t, test.expected, value, "the inserted value differs: %s", test.name)
+		}
+
+		// Parameters, which cannot be represented, are rejected.
+		errs := []struct {
+			name  string
+			param any
+			err   error
+		}{
+			{"uint64 overflow", uint64(math.MaxUint64), ErrParamOverflow},
+			{"uint overflow", uint(math.MaxInt64 + 1), ErrParamOverflow},
+			{"struct", struct{ A int }{1}, ErrUnsupportedParam},
+			{"channel", make(chan int), ErrUnsupportedParam},
+			{"complex", complex(1, 2), ErrUnsupportedParam},
+			{"map with channel", map[string]any{"c": make(chan int)}, ErrUnsupportedParam},
+		}
+		for _, test := range errs {
+			_, err := db.Exec("INSERT INTO test(i) VALUES(?)", test.param)
+			assert.ErrorIs(t, err, test.err, "inserting an invalid parameter should fail: %s", test.name)
+		}
+	})
+}
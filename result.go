@@ -0,0 +1,44 @@
+package immusql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/tauu/immusql/common"
+)
+
+// ImmuResult provides the metadata of the transactions committed
+// by executing a statement or by committing a transaction.
+type ImmuResult = common.Result
+
+// ErrNoResult is returned by LastResult, if neither a statement
+// has been executed nor a transaction has been committed.
+var ErrNoResult = common.ErrNoResult
+
+// LastResult returns the result of the last statement executed or transaction
+// committed using the connection. As database/sql hides the results of the
+// driver, the statement has to be executed using the same connection, e.g.
+//
+//	conn, err := db.Conn(ctx)
+//	...
+//	_, err = conn.ExecContext(ctx, "INSERT INTO test(name) VALUES(?)", "Maria")
+//	...
+//	res, err := immusql.LastResult(conn)
+func LastResult(conn *sql.Conn) (ImmuResult, error) {
+	var res ImmuResult
+	err := conn.Raw(func(driverConn any) error {
+		immuConn, ok := driverConn.(ImmuDBconn)
+		if !ok {
+			return errors.New("the connection is not an immudb connection")
+		}
+		res = immuConn.LastResult()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, ErrNoResult
+	}
+	return res, nil
+}
@@ -0,0 +1,174 @@
+package immusql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauu/immusql/common"
+)
+
+func TestLastResult(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		start := time.Now().Add(-time.Second)
+		ctx := context.Background()
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err, "retrieving an actual database connection failed")
+		defer conn.Close()
+		_, err = LastResult(conn)
+		assert.ErrorIs(t, err, ErrNoResult, "a result should not be available before executing a statement")
+
+		_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = conn.ExecContext(ctx, "INSERT INTO test(name) VALUES(?)", "Maria")
+		require.NoError(t, err, "inserting data should not fail")
+		first, err := LastResult(conn)
+		require.NoError(t, err, "retrieving the result of a statement should not fail")
+		require.Len(t, first.TxIDs(), 1, "the statement should have committed a single transaction")
+		require.Len(t, first.Timestamps(), 1, "the statement should have a single timestamp")
+		require.Len(t, first.Hashes(), 1, "the statement should have a single hash")
+		assert.False(t, first.Timestamps()[0].Before(start), "the transaction has a timestamp before the test")
+		assert.False(t, first.Timestamps()[0].After(time.Now()), "the transaction has a timestamp in the future")
+		assert.NotEqual(t, [sha256.Size]byte{}, first.Hashes()[0], "the transaction should have a hash")
+		assert.Equal(t, map[string]int64{"test": 1}, first.LastInsertedPKs(), "the statement should report the inserted primary key")
+
+		_, err = conn.ExecContext(ctx, "INSERT INTO test(name) VALUES(?)", "Marc")
+		require.NoError(t, err, "inserting data should not fail")
+		second, err := LastResult(conn)
+		require.NoError(t, err, "retrieving the result of a statement should not fail")
+		require.Len(t, second.TxIDs(), 1, "the statement should have committed a single transaction")
+		assert.Greater(t, second.TxIDs()[0], first.TxIDs()[0], "the second statement should have committed a later transaction")
+		assert.NotEqual(t, first.Hashes()[0], second.Hashes()[0], "the transactions should have different hashes")
+		assert.Equal(t, map[string]int64{"test": 2}, second.LastInsertedPKs(), "the statement should report the inserted primary key")
+
+		// The transaction ids identify the state of the database after the statement.
+		names := queryNames(t, WithTxSnapshot(ctx, first.TxIDs()[0]), db)
+		assert.Equal(t, []string{"Maria"}, names, "the snapshot of the first transaction should contain the first row")
+
+		// Transactions report their metadata after being committed.
+		tx, err := conn.BeginTx(ctx, nil)
+		require.NoError(t, err, "beginning a transaction should not fail")
+		_, err = tx.Exec("INSERT INTO test(name) VALUES(?)", "Mia")
+		require.NoError(t, err, "inserting data in a transaction should not fail")
+		// Statements in a transaction do not replace the last result.
+		last, err := LastResult(conn)
+		require.NoError(t, err, "retrieving the last result should not fail")
+		assert.Equal(t, second.TxIDs(), last.TxIDs(), "a statement in a transaction should not replace the last result")
+		_, err = tx.Exec("INSERT INTO test(name) VALUES(?)", "Nina")
+		require.NoError(t, err, "inserting data in a transaction should not fail")
+		require.NoError(t, tx.Commit(), "committing the transaction should not fail")
+		committed, err := LastResult(conn)
+		require.NoError(t, err, "retrieving the result of a commit should not fail")
+		require.Len(t, committed.TxIDs(), 1, "the commit should have committed a single transaction")
+		assert.Greater(t, committed.TxIDs()[0], second.TxIDs()[0], "the commit should have committed a later transaction")
+		assert.Len(t, committed.Timestamps(), 1, "the commit should have a single timestamp")
+		assert.Equal(t, map[string]int64{"test": 4}, committed.LastInsertedPKs(), "the commit should report the last inserted primary key")
+	})
+}
+
+func TestQueriedTables(t *testing.T) {
+	tests := map[string][]string{
+		"SELECT * FROM test":                                    {"test"},
+		"SELECT * FROM Test WHERE name = 'FROM x'":              {"test"},
+		"SELECT * FROM a INNER JOIN b ON a.id = b.id":           {"a", "b"},
+		"SELECT * FROM a AS b":                                  {"a"},
+		"SELECT * FROM a b WHERE b.id = 1":                      {"a"},
+		"SELECT * FROM a AS a":                                  {"a"},
+		"SELECT * FROM a AS b INNER JOIN b AS c ON b.id = c.id": {"a"},
+		"SELECT * FROM \"Quoted\" AS \"q\"":                     {"quoted"},
+		"SELECT * FROM (SELECT id FROM test) AS t":              {"test"},
+		"SELECT * FROM test SINCE TX 2 WHERE id = 1":            {"test"},
+		"SELECT id AS test FROM other":                          {"other"},
+		"SELECT * FROM /* comment */ test -- AS other\nLIMIT 1": {"test"},
+	}
+	for query, expected := range tests {
+		tables := make([]string, 0, len(expected))
+		for table := range common.QueriedTables(query) {
+			tables = append(tables, table)
+		}
+		assert.ElementsMatch(t, expected, tables, "unexpected tables for %s", query)
+	}
+}
+
+// lastInsertedPKs executes a statement using a connection and
+// returns the primary keys it inserted.
+func lastInsertedPKs(t *testing.T, conn *sql.Conn, query string) (int64, map[string]int64) {
+	res, err := conn.ExecContext(context.Background(), query)
+	require.NoError(t, err, "executing a statement should not fail")
+	id, err := res.LastInsertId()
+	require.NoError(t, err, "retrieving the last inserted id should not fail")
+	immuRes, err := LastResult(conn)
+	require.NoError(t, err, "retrieving the result of a statement should not fail")
+	return id, immuRes.LastInsertedPKs()
+}
+
+// lastInsertID executes a statement as part of a transaction
+// and returns the last id it inserted.
+func lastInsertID(t *testing.T, tx *sql.Tx, query string) int64 {
+	res, err := tx.Exec(query)
+	require.NoError(t, err, "executing a statement should not fail")
+	id, err := res.LastInsertId()
+	require.NoError(t, err, "retrieving the last inserted id should not fail")
+	return id
+}
+
+func TestLastInsertedPKs(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err, "retrieving an actual database connection failed")
+		defer conn.Close()
+		for _, table := range []string{"a", "b"} {
+			_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+"(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+			require.NoError(t, err, "creating a table should not fail")
+		}
+		_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS c(id INTEGER, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+
+		// Statements outside of a transaction.
+		id, pks := lastInsertedPKs(t, conn, "INSERT INTO a(name) VALUES('a1')")
+		assert.Equal(t, int64(1), id, "the last inserted id is unexpected")
+		assert.Equal(t, map[string]int64{"a": 1}, pks, "the inserted primary keys are unexpected")
+		id, pks = lastInsertedPKs(t, conn, "INSERT INTO a(name) VALUES('a2'); INSERT INTO b(name) VALUES('b1')")
+		assert.Equal(t, int64(-1), id, "the last inserted id should be ambiguous for multiple tables")
+		assert.Equal(t, map[string]int64{"a": 2, "b": 1}, pks, "the inserted primary keys are unexpected")
+		id, pks = lastInsertedPKs(t, conn, "INSERT INTO c(id, name) VALUES(1, 'c1')")
+		assert.Equal(t, int64(-1), id, "a table without auto increment key should not report an id")
+		assert.Empty(t, pks, "a table without auto increment key should not report a primary key")
+		id, pks = lastInsertedPKs(t, conn, "UPDATE a SET name = 'a' WHERE id = 1")
+		assert.Equal(t, int64(-1), id, "an update should not report an id")
+		assert.Empty(t, pks, "an update should not report a primary key")
+		id, pks = lastInsertedPKs(t, conn, `INSERT INTO "b"(name) VALUES('b2')`)
+		assert.Equal(t, int64(2), id, "the last inserted id of a quoted table is unexpected")
+		assert.Equal(t, map[string]int64{"b": 2}, pks, "the inserted primary keys of a quoted table are unexpected")
+
+		// Statements inside of a transaction only report their own keys using
+		// an embedded engine. The server reports them only after committing it.
+		pending := func(id int64) int64 {
+			if strings.HasSuffix(t.Name(), "/client") {
+				return -1
+			}
+			return id
+		}
+		tx, err := conn.BeginTx(ctx, nil)
+		require.NoError(t, err, "beginning a transaction should not fail")
+		defer tx.Rollback()
+		id = lastInsertID(t, tx, "INSERT INTO a(name) VALUES('a3')")
+		assert.Equal(t, pending(3), id, "the last inserted id is unexpected")
+		id = lastInsertID(t, tx, "INSERT INTO b(name) VALUES('b3'), ('b4')")
+		assert.Equal(t, pending(4), id, "the last inserted id is unexpected")
+		id = lastInsertID(t, tx, "INSERT INTO c(id, name) VALUES(2, 'c2')")
+		assert.Equal(t, int64(-1), id, "a table without auto increment key should not report an id")
+		id = lastInsertID(t, tx, "INSERT INTO a(name) VALUES('a4'); INSERT INTO b(name) VALUES('b5')")
+		assert.Equal(t, int64(-1), id, "the last inserted id should be ambiguous for multiple tables")
+		require.NoError(t, tx.Commit(), "committing the transaction should not fail")
+		committed, err := LastResult(conn)
+		require.NoError(t, err, "retrieving the result of a commit should not fail")
+		assert.Equal(t, map[string]int64{"a": 4, "b": 5}, committed.LastInsertedPKs(), "the commit should report the last inserted primary keys")
+	})
+}
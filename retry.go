@@ -0,0 +1,115 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/tauu/immusql/common"
+)
+
+// DefaultMaxAttempts is the number of times RunInTx runs a transaction,
+// if the options do not specify the maximum number of attempts.
+const DefaultMaxAttempts = 5
+
+// TxRetryOptions configures how RunInTx retries transactions.
+type TxRetryOptions struct {
+	// TxOptions are the options used to begin each transaction.
+	TxOptions *sql.TxOptions
+	// MaxAttempts is the maximum number of times the transaction is run.
+	// If it is not positive, DefaultMaxAttempts is used.
+	MaxAttempts int
+	// Backoff returns how long to wait before retrying the transaction
+	// after the given failed attempt, starting at 1. By default
+	// ExponentialBackoff(10*time.Millisecond, time.Second) is used.
+	Backoff func(attempt int) time.Duration
+	// OnRetry is called, if an attempt failed due to a conflict and
+	// the transaction is retried after waiting for the given delay.
+	OnRetry func(attempt int, err error, delay time.Duration)
+	// OnDone is called once RunInTx completes with the number of attempts,
+	// the total time spent and the error returned, if any.
+	OnDone func(attempts int, duration time.Duration, err error)
+}
+
+// ExponentialBackoff returns a backoff, which doubles the delay after each
+// attempt beginning with initial up to maxDelay. The delays are randomly shortened
+// by up to half, so that conflicting transactions are not retried in lockstep.
+func ExponentialBackoff(initial, maxDelay time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := initial
+		for i := 1; i < attempt && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		delay = min(delay, maxDelay)
+		if delay <= 0 {
+			return 0
+		}
+		return delay/2 + rand.N(delay/2+1)
+	}
+}
+
+// RunInTx runs fn in a transaction and commits it, if fn does not return
+// an error. Immudb aborts transactions, if data read by them is modified by
+// another transaction committed in the meantime. In this case the transaction
+// is rolled back and fn is run again in a new transaction, until the maximum
+// number of attempts is reached. Therefore fn must not have any side effects
+// besides the statements executed in the transaction. Any other error is
+// returned immediately. The options may be nil to use the defaults.
+func RunInTx(ctx context.Context, db *sql.DB, opts *TxRetryOptions, fn func(*sql.Tx) error) error {
+	if opts == nil {
+		opts = &TxRetryOptions{}
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(10*time.Millisecond, time.Second)
+	}
+	start := time.Now()
+	done := func(attempts int, err error) error {
+		if opts.OnDone != nil {
+			opts.OnDone(attempts, time.Since(start), err)
+		}
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, db, opts.TxOptions, fn)
+		if !common.IsTxConflict(err) {
+			return done(attempt, err)
+		}
+		if attempt >= maxAttempts {
+			return done(attempt, fmt.Errorf("transaction failed after %d attempts: %w", attempt, err))
+		}
+		delay := backoff(attempt)
+		if opts.OnRetry != nil {
+			opts.OnRetry(attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return done(attempt, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// runTx runs fn in a single transaction.
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	// Rolling back a committed transaction has no effect. It only ensures
+	// that the transaction is completed, if fn fails or panics.
+	defer tx.Rollback()
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,120 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauu/immusql/common"
+)
+
+// increment increases the counter in a transaction.
+func increment(tx *sql.Tx) error {
+	var count int64
+	err := tx.QueryRow("SELECT total FROM counter WHERE id = 1").Scan(&count)
+	if err != nil {
+		return err
+	}
+	// Give concurrent transactions a chance to modify the counter.
+	time.Sleep(time.Millisecond)
+	_, err = tx.Exec("UPDATE counter SET total = ? WHERE id = 1", count+1)
+	return err
+}
+
+func TestRunInTxParallel(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS counter(id INTEGER, total INTEGER, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO counter(id, total) VALUES(1, 0)")
+		require.NoError(t, err, "inserting data should not fail")
+
+		const writers, increments = 4, 5
+		var retries, attempts atomic.Int64
+		opts := &TxRetryOptions{
+			MaxAttempts: 100,
+			Backoff:     ExponentialBackoff(time.Millisecond, 20*time.Millisecond),
+			OnRetry: func(attempt int, err error, delay time.Duration) {
+				assert.True(t, common.IsTxConflict(err), "only conflicts should be retried")
+				retries.Add(1)
+			},
+			OnDone: func(n int, duration time.Duration, err error) {
+				attempts.Add(int64(n))
+			},
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < increments; j++ {
+					assert.NoError(t, RunInTx(ctx, db, opts, increment), "incrementing the counter should not fail")
+				}
+			}()
+		}
+		wg.Wait()
+
+		var count int64
+		require.NoError(t, db.QueryRow("SELECT total FROM counter WHERE id = 1").Scan(&count), "querying the counter should not fail")
+		assert.Equal(t, int64(writers*increments), count, "every increment should have been committed once")
+		assert.Greater(t, retries.Load(), int64(0), "parallel writers should have caused conflicts")
+		assert.Equal(t, writers*increments+retries.Load(), attempts.Load(), "the attempts should include the retries")
+	})
+}
+
+func TestRunInTxAttempts(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS counter(id INTEGER, total INTEGER, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO counter(id, total) VALUES(1, 0)")
+		require.NoError(t, err, "inserting data should not fail")
+
+		// Each attempt conflicts with a statement executed outside of the transaction.
+		var retries, attempts int
+		opts := &TxRetryOptions{
+			MaxAttempts: 3,
+			Backoff:     func(int) time.Duration { return 0 },
+			OnRetry:     func(int, error, time.Duration) { retries++ },
+			OnDone:      func(n int, _ time.Duration, _ error) { attempts = n },
+		}
+		err = RunInTx(ctx, db, opts, func(tx *sql.Tx) error {
+			var count int64
+			err := tx.QueryRow("SELECT total FROM counter WHERE id = 1").Scan(&count)
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec("UPDATE counter SET total = total + 1 WHERE id = 1")
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec("UPDATE counter SET total = ? WHERE id = 1", count+10)
+			return err
+		})
+		assert.True(t, common.IsTxConflict(err), "the transaction should have failed due to a conflict: %v", err)
+		assert.Equal(t, 2, retries, "the transaction should have been retried until the maximum number of attempts")
+		assert.Equal(t, 3, attempts, "the transaction should have been run the maximum number of times")
+		var count int64
+		require.NoError(t, db.QueryRow("SELECT total FROM counter WHERE id = 1").Scan(&count), "querying the counter should not fail")
+		assert.Equal(t, int64(3), count, "only the statements outside of the transaction should have been committed")
+
+		// Other errors are not retried.
+		errFailed := errors.New("failed")
+		attempts = 0
+		err = RunInTx(ctx, db, opts, func(tx *sql.Tx) error { return errFailed })
+		assert.ErrorIs(t, err, errFailed, "the error of the function should be returned")
+		assert.Equal(t, 1, attempts, "a failing function should not be retried")
+
+		// Successful transactions are committed.
+		err = RunInTx(ctx, db, nil, increment)
+		require.NoError(t, err, "incrementing the counter should not fail")
+		require.NoError(t, db.QueryRow("SELECT total FROM counter WHERE id = 1").Scan(&count), "querying the counter should not fail")
+		assert.Equal(t, int64(4), count, "the transaction should have been committed")
+	})
+}
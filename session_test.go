@@ -0,0 +1,96 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/codenotary/immudb/pkg/server/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionExpiredRetryClient(t *testing.T) {
+	// The server closes sessions shortly after they have been created.
+	opts := testServerOptions(t).WithSessionOptions(sessions.DefaultOptions().
+		WithSessionGuardCheckInterval(50 * time.Millisecond).
+		WithMaxSessionAgeTime(300 * time.Millisecond))
+	port, err := startServer(t, opts)
+	require.NoError(t, err, "starting the server should not fail")
+	db, err := sql.Open("immudb", clientDSN(t, port, nil))
+	require.NoError(t, err, "opening a connection should not fail")
+	defer db.Close()
+	// Only a single connection is used, so that each
+	// statement is executed using an expired session.
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec("CREATE TABLE test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+
+	// Statements are retried using a new session.
+	time.Sleep(500 * time.Millisecond)
+	_, err = db.Exec("INSERT INTO test(name) VALUES('Maria')")
+	assert.NoError(t, err, "executing a statement after the session expired should not fail")
+
+	// Queries are retried using a new session.
+	time.Sleep(500 * time.Millisecond)
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM test").Scan(&count)
+	assert.NoError(t, err, "querying data after the session expired should not fail")
+	assert.Equal(t, 1, count, "the row inserted after the session expired is missing")
+
+	// Transactions are begun using a new session.
+	time.Sleep(500 * time.Millisecond)
+	tx, err := db.Begin()
+	require.NoError(t, err, "beginning a transaction after the session expired should not fail")
+	_, err = tx.Exec("INSERT INTO test(name) VALUES('Marc')")
+	assert.NoError(t, err, "executing a statement in a transaction should not fail")
+	assert.NoError(t, tx.Commit(), "committing a transaction should not fail")
+
+	// A ping detects that the session has expired.
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err, "retrieving a connection should not fail")
+	defer conn.Close()
+	time.Sleep(500 * time.Millisecond)
+	err = conn.PingContext(context.Background())
+	assert.ErrorIs(t, err, driver.ErrBadConn, "a ping should fail after the session expired")
+}
+
+func TestSessionHeartBeatClient(t *testing.T) {
+	// The server closes sessions, which have been inactive for a short time.
+	opts := testServerOptions(t).WithSessionOptions(sessions.DefaultOptions().
+		WithSessionGuardCheckInterval(50 * time.Millisecond).
+		WithTimeout(300 * time.Millisecond))
+	port, err := startServer(t, opts)
+	require.NoError(t, err, "starting the server should not fail")
+
+	tests := []struct {
+		name               string
+		heartBeatFrequency string
+		valid              bool
+	}{
+		{"with rare heartbeats", "1h", false},
+		{"with frequent heartbeats", "50ms", true},
+	}
+	for _, test := range tests {
+		params := url.Values{"heartBeatFrequency": []string{test.heartBeatFrequency}}
+		db, err := sql.Open("immudb", clientDSN(t, port, params))
+		require.NoError(t, err, "opening a connection should not fail: %s", test.name)
+		conn, err := db.Conn(context.Background())
+		require.NoError(t, err, "retrieving a connection should not fail: %s", test.name)
+		require.NoError(t, conn.PingContext(context.Background()), "ping should not fail: %s", test.name)
+		// The connection stays idle for longer than the server keeps inactive sessions.
+		time.Sleep(time.Second)
+		var one int
+		err = conn.QueryRowContext(context.Background(), "SELECT 1").Scan(&one)
+		if test.valid {
+			assert.NoError(t, err, "querying data using a renewed session should not fail: %s", test.name)
+		} else {
+			assert.ErrorIs(t, err, driver.ErrBadConn, "querying data using an expired session should fail: %s", test.name)
+		}
+		conn.Close()
+		db.Close()
+	}
+}
@@ -0,0 +1,24 @@
+package immusql
+
+import (
+	"context"
+	"time"
+
+	"github.com/tauu/immusql/common"
+)
+
+// WithTxSnapshot returns a context for which queries read the database
+// as it was after the transaction with the given id had been committed.
+// Queries fail with ErrInvalidSnapshot, if the id is 0, and with
+// ErrSnapshotPeriod, if they already select a period of a table.
+func WithTxSnapshot(ctx context.Context, txID uint64) context.Context {
+	return common.WithSnapshot(ctx, common.Snapshot{TxID: txID})
+}
+
+// WithTimestamp returns a context for which queries read the database
+// as it was at the given time. immudb records the time of transactions
+// in seconds, so all transactions committed within the second are included.
+// Queries fail with ErrInvalidSnapshot, if the time is zero.
+func WithTimestamp(ctx context.Context, t time.Time) context.Context {
+	return common.WithSnapshot(ctx, common.Snapshot{Time: t})
+}
@@ -0,0 +1,155 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauu/immusql/common"
+)
+
+func TestApplySnapshot(t *testing.T) {
+	ctx := WithTxSnapshot(context.Background(), 5)
+	tests := map[string]string{
+		"SELECT * FROM test":                               "SELECT * FROM test UNTIL TX 5",
+		"select name from test as t where id = 1":          "select name from test UNTIL TX 5 as t where id = 1",
+		"SELECT * FROM a INNER JOIN b ON a.id = b.id":      "SELECT * FROM a UNTIL TX 5 INNER JOIN b UNTIL TX 5 ON a.id = b.id",
+		"SELECT * FROM test WHERE name = 'FROM x'":         "SELECT * FROM test UNTIL TX 5 WHERE name = 'FROM x'",
+		"SELECT * FROM test WHERE name = 'it''s FROM x'":   "SELECT * FROM test UNTIL TX 5 WHERE name = 'it''s FROM x'",
+		`SELECT * FROM "test" WHERE id = 1`:                `SELECT * FROM "test" UNTIL TX 5 WHERE id = 1`,
+		"SELECT * FROM TABLES()":                           "SELECT * FROM TABLES()",
+		"SELECT * FROM (SELECT id FROM test) AS t":         "SELECT * FROM (SELECT id FROM test UNTIL TX 5) AS t",
+		"SELECT * FROM\n\ttest -- FROM comment\nLIMIT 1":   "SELECT * FROM\n\ttest UNTIL TX 5 -- FROM comment\nLIMIT 1",
+		"SELECT * /* FROM comment */ FROM test":            "SELECT * /* FROM comment */ FROM test UNTIL TX 5",
+		"SELECT COUNT(*) FROM test WHERE id IN (1, 2)":     "SELECT COUNT(*) FROM test UNTIL TX 5 WHERE id IN (1, 2)",
+		"SELECT id FROM test ORDER BY id DESC LIMIT 10":    "SELECT id FROM test UNTIL TX 5 ORDER BY id DESC LIMIT 10",
+		"SELECT id FROM test WHERE name LIKE 'M' OR id=1":  "SELECT id FROM test UNTIL TX 5 WHERE name LIKE 'M' OR id=1",
+		"SELECT id, (SELECT 1) FROM test WHERE id = 1;":    "SELECT id, (SELECT 1) FROM test UNTIL TX 5 WHERE id = 1;",
+		"SELECT id FROM test WHERE name = 'unterminated":   "SELECT id FROM test UNTIL TX 5 WHERE name = 'unterminated",
+		"SELECT id FROM test /* unterminated FROM comment": "SELECT id FROM test UNTIL TX 5 /* unterminated FROM comment",
+	}
+	for query, expected := range tests {
+		rewritten, err := common.ApplySnapshot(ctx, query)
+		require.NoError(t, err, "rewriting %s should not fail", query)
+		assert.Equal(t, expected, rewritten, "unexpected rewritten query for %s", query)
+	}
+	// Tables with a period clause conflict with the snapshot.
+	for _, query := range []string{
+		"SELECT * FROM test SINCE TX 2",
+		`SELECT * FROM "test" until tx 3`,
+		"SELECT * FROM a INNER JOIN b BEFORE NOW() ON a.id = b.id",
+	} {
+		_, err := common.ApplySnapshot(ctx, query)
+		assert.ErrorIs(t, err, ErrSnapshotPeriod, "a period clause of %s should be rejected", query)
+	}
+	// Without a snapshot queries are not modified.
+	rewritten, err := common.ApplySnapshot(context.Background(), "SELECT * FROM test")
+	require.NoError(t, err, "rewriting a query without a snapshot should not fail")
+	assert.Equal(t, "SELECT * FROM test", rewritten, "queries without a snapshot should not be modified")
+	// Timestamps are converted to UTC.
+	ctx = WithTimestamp(context.Background(), time.Date(2024, 5, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60)))
+	rewritten, err = common.ApplySnapshot(ctx, "SELECT * FROM test")
+	require.NoError(t, err, "rewriting a query for a timestamp should not fail")
+	assert.Equal(t, "SELECT * FROM test UNTIL '2024-05-01 12:30:00'", rewritten, "unexpected rewritten query for a timestamp")
+	// A snapshot without a transaction or time is rejected.
+	for _, ctx := range []context.Context{WithTxSnapshot(context.Background(), 0), WithTimestamp(context.Background(), time.Time{})} {
+		_, err = common.ApplySnapshot(ctx, "SELECT * FROM test")
+		assert.ErrorIs(t, err, ErrInvalidSnapshot, "an empty snapshot should be rejected")
+	}
+}
+
+// queryNames returns the names stored in the test table ordered by id.
+func queryNames(t *testing.T, ctx context.Context, db *sql.DB) []string {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM test ORDER BY id")
+	require.NoError(t, err, "querying data should not fail")
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name), "scanning a row should not fail")
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err(), "iterating the rows should not fail")
+	return names
+}
+
+func TestTxSnapshot(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+		require.NoError(t, err, "inserting data should not fail")
+		_, err = db.Exec("UPDATE test SET name = ? WHERE id = 1", "Maria Magdalena")
+		require.NoError(t, err, "updating data should not fail")
+		_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Marc")
+		require.NoError(t, err, "inserting data should not fail")
+		_, err = db.Exec("DELETE FROM test WHERE id = 1")
+		require.NoError(t, err, "deleting data should not fail")
+
+		// Reading all transactions one after another reveals every state of the table.
+		current := queryNames(t, context.Background(), db)
+		require.Equal(t, []string{"Marc"}, current, "the current state of the table is unexpected")
+		var states [][]string
+		for txID := uint64(1); txID < 100; txID++ {
+			names := queryNames(t, WithTxSnapshot(context.Background(), txID), db)
+			if len(states) == 0 || !assert.ObjectsAreEqual(states[len(states)-1], names) {
+				states = append(states, names)
+			}
+		}
+		expected := [][]string{
+			{},
+			{"Maria"},
+			{"Maria Magdalena"},
+			{"Maria Magdalena", "Marc"},
+			{"Marc"},
+		}
+		assert.Equal(t, expected, states, "the snapshots should contain the previous states of the table")
+
+		// Quoted table names are read at the snapshot as well.
+		var count int64
+		err = db.QueryRowContext(WithTxSnapshot(context.Background(), 1), `SELECT COUNT(*) FROM "test"`).Scan(&count)
+		require.NoError(t, err, "querying a quoted table should not fail")
+		assert.Equal(t, int64(0), count, "the quoted table should be empty in the first transaction")
+
+		// Snapshots of transactions, which have not been committed yet, contain the current state.
+		names := queryNames(t, WithTxSnapshot(context.Background(), 1000), db)
+		assert.Equal(t, []string{"Marc"}, names, "a snapshot of a future transaction should contain the current state")
+
+		// Empty snapshots and period clauses conflicting with the snapshot are rejected.
+		_, err = db.QueryContext(WithTxSnapshot(context.Background(), 0), "SELECT name FROM test")
+		assert.ErrorIs(t, err, ErrInvalidSnapshot, "querying an empty snapshot should fail")
+		_, err = db.QueryContext(WithTxSnapshot(context.Background(), 1), "SELECT name FROM test SINCE TX 1")
+		assert.ErrorIs(t, err, ErrSnapshotPeriod, "querying a table with a period clause should fail")
+
+		// Snapshots are also read inside of transactions.
+		tx, err := db.Begin()
+		require.NoError(t, err, "beginning a transaction should not fail")
+		defer tx.Rollback()
+		rows, err := tx.QueryContext(WithTxSnapshot(context.Background(), 1), "SELECT name FROM test")
+		require.NoError(t, err, "querying data in a transaction should not fail")
+		assert.False(t, rows.Next(), "the table should be empty in the first transaction")
+		rows.Close()
+	})
+}
+
+func TestTimestampSnapshot(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+		require.NoError(t, err, "inserting data should not fail")
+		// immudb stores the time of transactions in seconds.
+		inserted := time.Now()
+		time.Sleep(1100 * time.Millisecond)
+		_, err = db.Exec("UPDATE test SET name = ? WHERE id = 1", "Maria Magdalena")
+		require.NoError(t, err, "updating data should not fail")
+
+		names := queryNames(t, WithTimestamp(context.Background(), inserted), db)
+		assert.Equal(t, []string{"Maria"}, names, "the snapshot should contain the inserted name")
+		names = queryNames(t, WithTimestamp(context.Background(), time.Now()), db)
+		assert.Equal(t, []string{"Maria Magdalena"}, names, "the snapshot should contain the updated name")
+	})
+}
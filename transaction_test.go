@@ -1,10 +1,14 @@
 package immusql
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tauu/immusql/common"
 )
 
 func TestTransactionSuccess(t *testing.T) {
@@ -224,3 +228,119 @@ func TestTransactionSimple(t *testing.T) {
 		assert.Equal(t, countBefore, countAfter, "An error happened in the transaction")
 	})
 }
+
+func TestTransactionReadOnly(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Maria")
+		require.NoError(t, err, "inserting data should not fail")
+
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		require.NoError(t, err, "beginning a read only transaction should not fail")
+		defer tx.Rollback()
+		var name string
+		err = tx.QueryRow("SELECT name FROM test WHERE id = 1").Scan(&name)
+		require.NoError(t, err, "querying data in a read only transaction should not fail")
+		assert.Equal(t, "Maria", name, "the queried name differs from the inserted one")
+		// Statements modifying data are rejected.
+		for _, statement := range []string{
+			"INSERT INTO test(name) VALUES('Marc')",
+			"UPDATE test SET name = 'Marc' WHERE id = 1",
+			"DELETE FROM test WHERE id = 1",
+			"CREATE TABLE other(id INTEGER, PRIMARY KEY id)",
+		} {
+			_, err = tx.Exec(statement)
+			assert.ErrorIs(t, err, ErrReadOnlyTx, "executing %s in a read only transaction should fail", statement)
+		}
+		// The transaction remains usable after rejecting a statement.
+		err = tx.QueryRow("SELECT name FROM test WHERE id = 1").Scan(&name)
+		require.NoError(t, err, "querying data after a rejected statement should not fail")
+		require.NoError(t, tx.Commit(), "committing a read only transaction should not fail")
+		assert.Equal(t, []string{"Maria"}, queryNames(t, ctx, db), "the read only transaction should not modify data")
+
+		// Later transactions may modify data again.
+		tx, err = db.BeginTx(ctx, nil)
+		require.NoError(t, err, "beginning a transaction should not fail")
+		_, err = tx.Exec("INSERT INTO test(name) VALUES('Marc')")
+		require.NoError(t, err, "inserting data in a regular transaction should not fail")
+		require.NoError(t, tx.Commit(), "committing a transaction should not fail")
+		assert.Equal(t, []string{"Maria", "Marc"}, queryNames(t, ctx, db), "the transaction should have inserted a row")
+	})
+}
+
+func TestTransactionIsolationLevel(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		supported := []sql.IsolationLevel{
+			sql.LevelDefault,
+			sql.LevelReadUncommitted,
+			sql.LevelReadCommitted,
+			sql.LevelRepeatableRead,
+			sql.LevelSnapshot,
+			sql.LevelSerializable,
+		}
+		for _, level := range supported {
+			tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: level})
+			require.NoError(t, err, "beginning a transaction with isolation level %s should not fail", level)
+			_, err = tx.Exec("INSERT INTO test(name) VALUES(?)", level.String())
+			assert.NoError(t, err, "inserting data with isolation level %s should not fail", level)
+			assert.NoError(t, tx.Commit(), "committing a transaction with isolation level %s should not fail", level)
+		}
+		for _, level := range []sql.IsolationLevel{sql.LevelWriteCommitted, sql.LevelLinearizable} {
+			_, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: level})
+			assert.ErrorIs(t, err, ErrIsolationLevelNotSupported, "beginning a transaction with isolation level %s should fail", level)
+		}
+		var count int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM test").Scan(&count), "counting the rows should not fail")
+		assert.Equal(t, len(supported), count, "every transaction should have inserted a row")
+	})
+}
+
+func TestCheckIsolation(t *testing.T) {
+	// Lower isolation levels use unsafe MVCC, all others use
+	// the default options of immudb transactions.
+	levels := map[sql.IsolationLevel]bool{
+		sql.LevelDefault:         false,
+		sql.LevelReadUncommitted: true,
+		sql.LevelReadCommitted:   true,
+		sql.LevelRepeatableRead:  false,
+		sql.LevelSnapshot:        false,
+		sql.LevelSerializable:    false,
+	}
+	for level, expected := range levels {
+		unsafeMVCC, err := common.CheckIsolation(driver.IsolationLevel(level))
+		assert.NoError(t, err, "isolation level %s should be accepted", level)
+		assert.Equal(t, expected, unsafeMVCC, "isolation level %s uses unexpected options", level)
+	}
+	for _, level := range []sql.IsolationLevel{sql.LevelWriteCommitted, sql.LevelLinearizable} {
+		_, err := common.CheckIsolation(driver.IsolationLevel(level))
+		assert.ErrorIs(t, err, ErrIsolationLevelNotSupported, "isolation level %s should be rejected", level)
+	}
+}
+
+func TestRepeatableReadConflict(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		ctx := context.Background()
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS counter(id INTEGER, total INTEGER, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO counter(id, total) VALUES(1, 0)")
+		require.NoError(t, err, "inserting data should not fail")
+		// Concurrent modifications of the data read are detected.
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+		require.NoError(t, err, "beginning a transaction should not fail")
+		defer tx.Rollback()
+		var total int64
+		require.NoError(t, tx.QueryRow("SELECT total FROM counter WHERE id = 1").Scan(&total), "querying the counter should not fail")
+		_, err = db.Exec("UPDATE counter SET total = total + 1 WHERE id = 1")
+		require.NoError(t, err, "updating the counter outside of the transaction should not fail")
+		_, err = tx.Exec("UPDATE counter SET total = ? WHERE id = 1", total+10)
+		if err == nil {
+			err = tx.Commit()
+		}
+		assert.True(t, common.IsTxConflict(err), "the transaction should have failed due to a conflict: %v", err)
+	})
+}
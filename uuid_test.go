@@ -0,0 +1,91 @@
+package immusql
+
+import (
+	"database/sql"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUUIDFormat(t *testing.T) {
+	// Create a single test server used by the client connections of all formats.
+	port, err := startServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the test server should not fail")
+
+	formats := []struct {
+		format UUIDFormat
+		// value converts a uuid into the value expected for the format.
+		value func(u uuid.UUID) any
+	}{
+		{UUIDFormatString, func(u uuid.UUID) any { return u.String() }},
+		{UUIDFormatBytes, func(u uuid.UUID) any { return u[:] }},
+		{UUIDFormatNative, func(u uuid.UUID) any { return u[:] }},
+	}
+	for _, test := range formats {
+		params := url.Values{"uuidFormat": {test.format.String()}}
+		dsns := map[string]string{
+			"embedded": (&url.URL{Scheme: "immudbe", Path: t.TempDir(), RawQuery: params.Encode()}).String(),
+			"client":   clientDSN(t, port, params),
+		}
+		for backend, dsn := range dsns {
+			t.Run(test.format.String()+"/"+backend, func(t *testing.T) {
+				db, err := sql.Open("immudb", dsn)
+				require.NoError(t, err, "opening a connection should not fail")
+				defer db.Close()
+
+				_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, u UUID, PRIMARY KEY id)")
+				require.NoError(t, err, "creating a table should not fail")
+				id := uuid.New()
+				// The client connections of all formats share a database,
+				// so rows are selected by the id of the inserted row.
+				res, err := db.Exec("INSERT INTO test(u) VALUES(?)", id)
+				require.NoError(t, err, "inserting a uuid should not fail")
+				rowID, err := res.LastInsertId()
+				require.NoError(t, err, "retrieving the id of the inserted row should not fail")
+
+				// The uuid is returned in the configured format.
+				var value any
+				err = db.QueryRow("SELECT u FROM test WHERE id = ?", rowID).Scan(&value)
+				require.NoError(t, err, "querying the uuid should not fail")
+				assert.Equal(t, test.value(id), value, "the uuid is not returned in the configured format")
+
+				// The value can be scanned into the type reported for the column.
+				rows, err := db.Query("SELECT u FROM test WHERE id = ?", rowID)
+				require.NoError(t, err, "querying the uuid should not fail")
+				defer rows.Close()
+				types, err := rows.ColumnTypes()
+				require.NoError(t, err, "retrieving the column types should not fail")
+				scanType := types[0].ScanType()
+				require.True(t, rows.Next(), "the query should return a row")
+				dest := reflect.New(scanType)
+				require.NoError(t, rows.Scan(dest.Interface()), "scanning into the scan type %v should not fail", scanType)
+				expected := test.value(id)
+				if test.format == UUIDFormatNative {
+					expected = id
+				}
+				assert.Equal(t, expected, dest.Elem().Interface(), "the uuid scanned into the scan type differs")
+				require.NoError(t, rows.Close(), "closing the rows should not fail")
+
+				// The returned value can be inserted again.
+				res, err = db.Exec("INSERT INTO test(u) VALUES(?)", value)
+				require.NoError(t, err, "inserting the returned uuid should not fail")
+				copyID, err := res.LastInsertId()
+				require.NoError(t, err, "retrieving the id of the inserted row should not fail")
+				var copied any
+				err = db.QueryRow("SELECT u FROM test WHERE id = ?", copyID).Scan(&copied)
+				require.NoError(t, err, "querying the inserted uuid should not fail")
+				assert.Equal(t, value, copied, "the returned uuid changed after inserting it again")
+
+				// A uuid.UUID can be scanned from all formats.
+				var scanned uuid.UUID
+				err = db.QueryRow("SELECT u FROM test WHERE id = ?", rowID).Scan(&scanned)
+				require.NoError(t, err, "scanning the uuid should not fail")
+				assert.Equal(t, id, scanned, "the scanned uuid differs from the inserted one")
+			})
+		}
+	}
+}
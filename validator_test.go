@@ -0,0 +1,102 @@
+package immusql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isValid reports if the driver connection of conn is valid.
+func isValid(t *testing.T, conn *sql.Conn) bool {
+	var valid bool
+	err := conn.Raw(func(driverConn any) error {
+		validator, ok := driverConn.(driver.Validator)
+		require.True(t, ok, "the connection should implement driver.Validator")
+		valid = validator.IsValid()
+		return nil
+	})
+	require.NoError(t, err, "accessing the driver connection should not fail")
+	return valid
+}
+
+// fillPool opens n connections at once and returns them to the pool.
+func fillPool(t *testing.T, db *sql.DB, n int) {
+	conns := make([]*sql.Conn, n)
+	for i := range conns {
+		conn, err := db.Conn(context.Background())
+		require.NoError(t, err, "retrieving a connection should not fail")
+		require.NoError(t, conn.PingContext(context.Background()), "ping should not fail")
+		conns[i] = conn
+	}
+	for _, conn := range conns {
+		require.NoError(t, conn.Close(), "returning a connection to the pool should not fail")
+	}
+	require.Equal(t, n, db.Stats().Idle, "all connections should be idle in the pool")
+}
+
+func TestValidatorClient(t *testing.T) {
+	port, stop, err := startStoppableServer(t, testServerOptions(t))
+	require.NoError(t, err, "starting the server should not fail")
+	db, err := sql.Open("immudb", clientDSN(t, port, nil))
+	require.NoError(t, err, "opening a connection should not fail")
+	defer db.Close()
+	db.SetMaxIdleConns(3)
+	fillPool(t, db, 3)
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err, "retrieving a connection should not fail")
+	assert.True(t, isValid(t, conn), "a connection to a running server should be valid")
+
+	// The server is killed, while the pool is in use.
+	stop()
+	_, err = conn.ExecContext(context.Background(), "CREATE TABLE test(id INTEGER, PRIMARY KEY id)")
+	assert.Error(t, err, "executing a statement without a server should fail")
+	assert.NotErrorIs(t, err, driver.ErrBadConn, "a statement, which may have been executed, must not be retried")
+	assert.False(t, isValid(t, conn), "a connection to an unavailable server should not be valid")
+	// The connection is discarded instead of being returned to the pool.
+	require.NoError(t, conn.Close(), "releasing the connection should not fail")
+	assert.Equal(t, 2, db.Stats().OpenConnections, "the invalid connection should have left the pool")
+
+	// Each idle connection leaves the pool, once it failed.
+	for i := 0; i < 2; i++ {
+		_, err = db.Exec("CREATE TABLE test(id INTEGER, PRIMARY KEY id)")
+		assert.Error(t, err, "executing a statement without a server should fail")
+	}
+	assert.Equal(t, 0, db.Stats().OpenConnections, "all connections should have left the pool")
+}
+
+func TestValidatorEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	dsn := "immudbe://" + filepath.Join(dir, "defaultdb")
+	db, err := sql.Open("immudb", dsn)
+	require.NoError(t, err, "opening a connection should not fail")
+	defer db.Close()
+	db.SetMaxIdleConns(3)
+	_, err = db.Exec("CREATE TABLE test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	fillPool(t, db, 3)
+
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err, "retrieving a connection should not fail")
+	assert.True(t, isValid(t, conn), "a connection to an open store should be valid")
+
+	// The store is closed, while the pool is in use.
+	require.NoError(t, CloseEmbedded(dir), "closing the embedded store should not fail")
+	assert.False(t, isValid(t, conn), "a connection to a closed store should not be valid")
+	_, err = conn.ExecContext(context.Background(), "INSERT INTO test(name) VALUES('Maria')")
+	assert.Error(t, err, "inserting data into a closed store should fail")
+	// The connection is discarded instead of being returned to the pool.
+	require.NoError(t, conn.Close(), "releasing the connection should not fail")
+	assert.Equal(t, 2, db.Stats().OpenConnections, "the invalid connection should have left the pool")
+
+	// The idle connections are discarded before they are reused,
+	// so the statement is executed using a new connection, which opens the store again.
+	_, err = db.Exec("INSERT INTO test(name) VALUES('Maria')")
+	assert.NoError(t, err, "inserting data using a new connection should not fail")
+	assert.Equal(t, 1, db.Stats().OpenConnections, "only the new connection should be left in the pool")
+}
@@ -0,0 +1,145 @@
+package immusql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// verifiedGet calls VerifiedGet on a connection of the database.
+func verifiedGet(t *testing.T, db *sql.DB, table string, pk ...any) (map[string]any, error) {
+	conn, err := db.Conn(context.Background())
+	require.NoError(t, err, "retrieving an actual database connection failed")
+	defer conn.Close()
+	var values map[string]any
+	err = conn.Raw(func(driverConn any) error {
+		immuConn, ok := driverConn.(ImmuDBconn)
+		require.True(t, ok, "driver object of database connection does not satisfiy ImmuDBconn interface")
+		var err error
+		values, err = immuConn.VerifiedGet(table, pk...)
+		return err
+	})
+	return values, err
+}
+
+func TestVerifiedGet(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, age INTEGER, date TIMESTAMP, PRIMARY KEY id)")
+		require.NoError(t, err, "creating a table should not fail")
+		date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		for _, name := range []string{"Maria", "Marc", "Mia"} {
+			_, err = db.Exec("INSERT INTO test(name, age, date) VALUES(?, ?, ?)", name, 30, date)
+			require.NoError(t, err, "inserting data should not fail")
+		}
+		_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "Nobody")
+		require.NoError(t, err, "inserting data should not fail")
+
+		// Verified rows contain all columns.
+		values, err := verifiedGet(t, db, "test", 2)
+		require.NoError(t, err, "retrieving a verified row should not fail")
+		assert.Equal(t, int64(2), values["id"], "the verified row has an unexpected id")
+		assert.Equal(t, "Marc", values["name"], "the verified row has an unexpected name")
+		assert.Equal(t, int64(30), values["age"], "the verified row has an unexpected age")
+		assert.True(t, date.Equal(values["date"].(time.Time)), "the verified row has an unexpected date")
+
+		// Columns without a value are NULL.
+		values, err = verifiedGet(t, db, "test", 4)
+		require.NoError(t, err, "retrieving a verified row should not fail")
+		assert.Equal(t, "Nobody", values["name"], "the verified row has an unexpected name")
+		assert.Contains(t, values, "age", "the verified row should contain all columns")
+		assert.Nil(t, values["age"], "the age of the verified row should be NULL")
+
+		// Rows are verified after further transactions.
+		_, err = db.Exec("UPDATE test SET name = ? WHERE id = ?", "Maria Magdalena", 1)
+		require.NoError(t, err, "updating data should not fail")
+		values, err = verifiedGet(t, db, "test", 1)
+		require.NoError(t, err, "retrieving a verified row should not fail")
+		assert.Equal(t, "Maria Magdalena", values["name"], "the verified row should contain the updated name")
+		values, err = verifiedGet(t, db, "test", 3)
+		require.NoError(t, err, "retrieving a verified row should not fail")
+		assert.Equal(t, "Mia", values["name"], "the verified row has an unexpected name")
+
+		// Missing and deleted rows are not found.
+		_, err = verifiedGet(t, db, "test", 10)
+		assert.ErrorIs(t, err, sql.ErrNoRows, "retrieving a missing row should fail")
+		_, err = db.Exec("DELETE FROM test WHERE id = ?", 3)
+		require.NoError(t, err, "deleting data should not fail")
+		_, err = verifiedGet(t, db, "test", 3)
+		assert.ErrorIs(t, err, sql.ErrNoRows, "retrieving a deleted row should fail")
+
+		// The number of primary key values must match the primary key.
+		_, err = verifiedGet(t, db, "test", 1, "Maria")
+		assert.Error(t, err, "retrieving a row with too many primary key values should fail")
+		_, err = verifiedGet(t, db, "unknown", 1)
+		assert.Error(t, err, "retrieving a row of an unknown table should fail")
+		_, err = verifiedGet(t, db, "test WHERE 1 = 1 OR id", 1)
+		assert.Error(t, err, "retrieving a row of a table with an invalid name should fail")
+	})
+}
+
+func TestVerifiedGetCompositeKey(t *testing.T) {
+	runTest(t, func(t *testing.T, db *sql.DB) {
+		_, err := db.Exec("CREATE TABLE IF NOT EXISTS test(name VARCHAR[32], id INTEGER, age INTEGER, PRIMARY KEY (id, name))")
+		require.NoError(t, err, "creating a table should not fail")
+		_, err = db.Exec("INSERT INTO test(id, name, age) VALUES(1, 'Maria', 30), (1, 'Marc', 40)")
+		require.NoError(t, err, "inserting data should not fail")
+		// The values are given in the order of the primary key.
+		values, err := verifiedGet(t, db, "test", 1, "Marc")
+		require.NoError(t, err, "retrieving a verified row should not fail")
+		assert.Equal(t, int64(40), values["age"], "the verified row has an unexpected age")
+		_, err = verifiedGet(t, db, "test", 2, "Marc")
+		assert.ErrorIs(t, err, sql.ErrNoRows, "retrieving a missing row should fail")
+	})
+}
+
+func TestVerifiedGetTampered(t *testing.T) {
+	path := t.TempDir()
+	dsn := "immudbe://" + filepath.Join(path, "defaultdb")
+	db, err := sql.Open("immudb", dsn)
+	require.NoError(t, err, "opening the database should not fail")
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS test(id INTEGER AUTO_INCREMENT, name VARCHAR, PRIMARY KEY id)")
+	require.NoError(t, err, "creating a table should not fail")
+	_, err = db.Exec("INSERT INTO test(name) VALUES(?)", "original-value")
+	require.NoError(t, err, "inserting data should not fail")
+	// Querying the row waits until it has been indexed. Otherwise immudb
+	// would index the modified value while reopening the store and get stuck.
+	var name string
+	err = db.QueryRow("SELECT name FROM test WHERE id = 1").Scan(&name)
+	require.NoError(t, err, "querying data should not fail")
+	require.NoError(t, db.Close(), "closing the database should not fail")
+	require.NoError(t, CloseEmbedded(path), "closing the store should not fail")
+
+	// Modify the stored value directly in the files of the store.
+	var tampered bool
+	err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if !bytes.Contains(content, []byte("original-value")) {
+			return nil
+		}
+		tampered = true
+		content = bytes.ReplaceAll(content, []byte("original-value"), []byte("modified-value"))
+		return os.WriteFile(file, content, info.Mode())
+	})
+	require.NoError(t, err, "modifying the store should not fail")
+	require.True(t, tampered, "the value should be found in the files of the store")
+
+	// The modification is detected when verifying the row.
+	db, err = sql.Open("immudb", dsn)
+	require.NoError(t, err, "opening the database should not fail")
+	defer db.Close()
+	_, err = verifiedGet(t, db, "test", 1)
+	assert.ErrorIs(t, err, ErrVerificationFailed, "retrieving a modified row should fail")
+}